@@ -0,0 +1,252 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go_update_bulk/utils"
+	"time"
+)
+
+// AuditEntry is before and after image of a row changed by bulk update
+//
+// Before is nil when the row is inserted, e.g. by upsert, images only contain updated fields and keys
+type AuditEntry struct {
+	JobID     string         `json:"job_id"`
+	Operation string         `json:"operation"`
+	Strategy  string         `json:"strategy"`
+	Table     string         `json:"table"`
+	Keys      map[string]any `json:"keys"`
+	Before    map[string]any `json:"before"`
+	After     map[string]any `json:"after"`
+	Time      time.Time      `json:"time"`
+}
+
+// AuditSink is used to write audit entries of every page, see WithAudit
+//
+// tx is the transaction of the page, so entries written using tx are committed together with the update,
+// when WriteAudit returns error the page is rolled back
+type AuditSink interface {
+	WriteAudit(ctx context.Context, tx SQL, entries []AuditEntry) error
+}
+
+// AuditFunc is used to write audit entries using a function, e.g. to send them to a queue
+type AuditFunc func(ctx context.Context, tx SQL, entries []AuditEntry) error
+
+func (f AuditFunc) WriteAudit(ctx context.Context, tx SQL, entries []AuditEntry) error {
+	return f(ctx, tx, entries)
+}
+
+type auditTable struct {
+	table string
+}
+
+// NewAuditTable is used to write audit entries into the table in the same transaction as the update
+//
+// the table needs columns job_id, table_name, operation, strategy, row_keys, before_image, after_image and created_at,
+// images are stored as JSON text, rows are inserted as supporting query of the page, not as CreateBulk operation
+func NewAuditTable(table string) AuditSink {
+	return &auditTable{table: table}
+}
+
+func (a *auditTable) WriteAudit(ctx context.Context, tx SQL, entries []AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	rows := make([]map[string]any, 0, len(entries))
+	for _, entry := range entries {
+		keys, err := json.Marshal(entry.Keys)
+		if err != nil {
+			return fmt.Errorf("failed encode keys: %w", err)
+		}
+		var before any
+		if entry.Before != nil {
+			encoded, err := json.Marshal(entry.Before)
+			if err != nil {
+				return fmt.Errorf("failed encode before image: %w", err)
+			}
+			before = string(encoded)
+		}
+		after, err := json.Marshal(entry.After)
+		if err != nil {
+			return fmt.Errorf("failed encode after image: %w", err)
+		}
+		rows = append(rows, map[string]any{
+			"job_id":       entry.JobID,
+			"table_name":   entry.Table,
+			"operation":    entry.Operation,
+			"strategy":     entry.Strategy,
+			"row_keys":     string(keys),
+			"before_image": before,
+			"after_image":  string(after),
+			"created_at":   entry.Time,
+		})
+	}
+	s, ok := tx.(*sql)
+	if !ok {
+		return tx.CreateBulkContext(ctx, a.table, rows, len(rows[0]))
+	}
+
+	// supporting query of the audited page, so it isn't reported as CreateBulk operation
+	query, _, err := utils.CreateQuery(s.dialect, a.table, rows[0])
+	if err != nil {
+		return fmt.Errorf("failed build query %w", err)
+	}
+	event := QueryEvent{Operation: entries[0].Operation, Strategy: entries[0].Strategy, Table: a.table}
+	pageSize := utils.BulkMaxDataSize(len(rows), len(rows)*len(rows[0]))
+	for _, page := range utils.PagedData(rows, pageSize) {
+		event.Rows = len(page)
+		event.Placeholders = len(page) * len(rows[0])
+		if _, err := s.namedExec(ctx, event, query, page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jobIDKey struct{}
+
+// ContextWithJobID is used to tag audit entries of bulk updates executed using ctx with jobID,
+// bulk update without job ID generates one, so entries of the same call share it
+func ContextWithJobID(ctx context.Context, jobID string) context.Context {
+	return context.WithValue(ctx, jobIDKey{}, jobID)
+}
+
+// JobIDFromContext is used to get job ID of ContextWithJobID, empty when there is none
+func JobIDFromContext(ctx context.Context) string {
+	jobID, _ := ctx.Value(jobIDKey{}).(string)
+	return jobID
+}
+
+// auditJob is used to make sure every page of the operation uses the same job ID
+func (s *sql) auditJob(ctx context.Context) context.Context {
+	if s.audit == nil || JobIDFromContext(ctx) != "" {
+		return ctx
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		id = []byte(time.Now().Format("20060102150405.000000000"))
+	}
+	return ContextWithJobID(ctx, hex.EncodeToString(id))
+}
+
+// rejectAudit is used by strategies that don't update rows by page
+func (s *sql) rejectAudit(strategy string) error {
+	if s.audit != nil {
+		return fmt.Errorf("%s doesn't support audit, use case, values or upsert", strategy)
+	}
+	return nil
+}
+
+// audited is used to execute fn that updates data of a page inside transaction,
+// when WithAudit is used the rows of data keys are locked and selected using SELECT ... FOR UPDATE before fn,
+// then selected again after fn, and rows that are changed are written to the sink
+func (s *sql) audited(ctx context.Context, op OperationEvent, data []map[string]any, keyEdits []string, fn func() error) error {
+	if s.audit == nil {
+		return fn()
+	}
+
+	fields := map[string]bool{}
+	for _, key := range keyEdits {
+		fields[key] = true
+	}
+	for _, item := range data {
+		for field := range utils.ResolveOptional(item) {
+			fields[field] = true
+		}
+	}
+	keys := append([]string{}, keyEdits...)
+	query, binds, err := utils.SelectKeysQuery(s.dialect, op.Table, utils.SortMapKeys(fields), data, keys, true)
+	if err != nil {
+		return fmt.Errorf("failed to build audit query: %w", err)
+	}
+	event := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: op.Table}
+
+	beforeRows, err := s.namedSelectMaps(ctx, event, query, binds)
+	if err != nil {
+		return fmt.Errorf("error when select before image: %w", err)
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	afterRows, err := s.namedSelectMaps(ctx, event, query, binds)
+	if err != nil {
+		return fmt.Errorf("error when select after image: %w", err)
+	}
+
+	before, err := rowsByKey(beforeRows, keyEdits)
+	if err != nil {
+		return err
+	}
+	after, err := rowsByKey(afterRows, keyEdits)
+	if err != nil {
+		return err
+	}
+
+	jobID := JobIDFromContext(ctx)
+	now := time.Now()
+	entries := []AuditEntry{}
+	for _, item := range data {
+		key, err := utils.RowKey(item, keyEdits)
+		if err != nil {
+			return err
+		}
+		afterRow, ok := after[key]
+		if !ok {
+			continue
+		}
+		// the same key is only written once
+		delete(after, key)
+		beforeRow, existed := before[key]
+		if existed && utils.DiffRow(beforeRow, afterRow, keyEdits) == nil {
+			continue
+		}
+		entry := AuditEntry{
+			JobID:     jobID,
+			Operation: op.Operation,
+			Strategy:  op.Strategy,
+			Table:     op.Table,
+			Keys:      utils.PickFields(item, keyEdits),
+			After:     auditImage(afterRow),
+			Time:      now,
+		}
+		if existed {
+			entry.Before = auditImage(beforeRow)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := s.audit.WriteAudit(ctx, s, entries); err != nil {
+		return fmt.Errorf("failed write audit: %w", err)
+	}
+	return nil
+}
+
+// rowsByKey is used to index selected rows using RowKey
+func rowsByKey(rows []map[string]any, keyEdits []string) (map[string]map[string]any, error) {
+	indexed := make(map[string]map[string]any, len(rows))
+	for _, row := range rows {
+		key, err := utils.RowKey(row, keyEdits)
+		if err != nil {
+			return nil, err
+		}
+		indexed[key] = row
+	}
+	return indexed, nil
+}
+
+// auditImage is used to convert selected row to JSON friendly map, text protocol returns []byte
+func auditImage(row map[string]any) map[string]any {
+	image := make(map[string]any, len(row))
+	for field, value := range row {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		image[field] = value
+	}
+	return image
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	selectQuery := "SELECT `age`, `id` FROM `user` WHERE `id` IN \\(\\?, \\?\\) FOR UPDATE"
+	updateQuery := "UPDATE `user` SET `age` = \\( CASE WHEN `id` = \\? THEN \\? WHEN `id` = \\? THEN \\? ELSE `age` END \\) WHERE `id` IN \\(\\?, \\?\\)"
+	data := func() []map[string]any {
+		return []map[string]any{{"id": 1, "age": 21}, {"id": 2, "age": 30}}
+	}
+	before := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"age", "id"}).AddRow([]byte("20"), []byte("1")).AddRow([]byte("30"), []byte("2"))
+	}
+	after := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"age", "id"}).AddRow([]byte("21"), []byte("1")).AddRow([]byte("30"), []byte("2"))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		entries := []AuditEntry{}
+		sink := AuditFunc(func(ctx context.Context, tx SQL, page []AuditEntry) error {
+			entries = append(entries, page...)
+			return nil
+		})
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithAudit(sink))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(before())
+		mock.ExpectExec(updateQuery).WithArgs(1, 21, 2, 30, 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(after())
+		mock.ExpectCommit()
+
+		ctx := ContextWithJobID(context.Background(), "job-1")
+		err = db.UpdateBulkContext(ctx, "user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// data 2 is unchanged
+		require.Len(t, entries, 1)
+		assert.Equal(t, "job-1", entries[0].JobID)
+		assert.Equal(t, "UpdateBulk", entries[0].Operation)
+		assert.Equal(t, "case", entries[0].Strategy)
+		assert.Equal(t, map[string]any{"id": 1}, entries[0].Keys)
+		assert.Equal(t, map[string]any{"age": "20", "id": "1"}, entries[0].Before)
+		assert.Equal(t, map[string]any{"age": "21", "id": "1"}, entries[0].After)
+	})
+
+	t.Run("table", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		hook := &recordHook{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithAudit(NewAuditTable("audit_log")), WithHooks(hook))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(before())
+		mock.ExpectExec(updateQuery).WithArgs(1, 21, 2, 30, 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(after())
+		mock.ExpectExec("INSERT INTO `audit_log` \\(`after_image`, `before_image`, `created_at`, `job_id`, `operation`, `row_keys`, `strategy`, `table_name`\\)").
+			WithArgs(`{"age":"21","id":"1"}`, `{"age":"20","id":"1"}`, sqlmock.AnyArg(), sqlmock.AnyArg(), "UpdateBulk", `{"id":1}`, "case", "user").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err = db.UpdateBulk("user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// audit insert is supporting query of the page, not other operation
+		require.Len(t, hook.operations, 1)
+		assert.Equal(t, "UpdateBulk", hook.operations[0].Operation)
+		require.Len(t, hook.after, 4)
+		assert.Equal(t, "audit_log", hook.after[3].Table)
+		assert.Equal(t, 0, hook.after[3].Page)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		sink := AuditFunc(func(ctx context.Context, tx SQL, entries []AuditEntry) error {
+			return errors.New("sink is unavailable")
+		})
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithAudit(sink))
+		require.Nil(t, err)
+
+		// update is rolled back when the audit can't be written
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(before())
+		mock.ExpectExec(updateQuery).WithArgs(1, 21, 2, 30, 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2).WillReturnRows(after())
+		mock.ExpectRollback()
+
+		err = db.UpdateBulk("user", data(), []string{"id"}, 2)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		assert.NotNil(t, db.UpdateParallel("user", data(), []string{"id"}, 2))
+		assert.NotNil(t, db.UpdateSequential("user", data(), []string{"id"}, 2))
+		assert.NotNil(t, db.UpdateTempTable("user", data(), []string{"id"}, 2))
+	})
+}
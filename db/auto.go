@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"time"
+)
+
+const (
+	// autoTempTableRows is minimum rows that UpdateAuto updates using temporary table
+	autoTempTableRows = 10000
+	// autoManyFields is maximum fields that UpdateAuto still updates using CASE
+	autoManyFields = 20
+)
+
+func (s *sql) UpdateAuto(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateAutoContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+// UpdateAutoContext is used to update data using strategy chosen from rows, fields, keyEdits and dialect,
+// the chosen strategy and the reason are logged
+//
+// when WithCalibration is used, candidate strategies update small samples of data first,
+// then the fastest one updates the rest
+func (s *sql) UpdateAutoContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	// expression can only be inlined by CASE and single update, version can only be checked by them as well,
+	// join strategies need the same fields for every data, otherwise missing field would be updated to NULL
+	unjoinable := ""
+	if _, ok := s.versionOf(table); ok {
+		unjoinable = "expression or version can't be joined"
+	}
+	for _, item := range data {
+		if _, ok := utils.HasExpr(item); ok {
+			unjoinable = "expression or version can't be joined"
+			break
+		}
+	}
+	if unjoinable == "" && len(utils.GroupByFields(data)) > 1 {
+		unjoinable = "rows with different fields can't be joined"
+	}
+
+	name, reason := s.chooseStrategy(len(data), fieldSize, keyEdits, unjoinable)
+
+	candidates := s.calibrationCandidates(unjoinable)
+	sampleSize := s.calibration
+	if sampleSize > 0 && len(candidates) > 1 && len(data) > sampleSize*len(candidates)*2 {
+		fastest, perRow, err := s.calibrate(ctx, table, data[:sampleSize*len(candidates)], keyEdits, fieldSize, candidates)
+		if err != nil {
+			return fmt.Errorf("failed calibration: %w", err)
+		}
+		data = data[sampleSize*len(candidates):]
+		name, reason = fastest, fmt.Sprintf("fastest calibration with %s per row", perRow)
+	}
+
+	s.logger.Printf("UpdateAuto %s chose %s: %s", table, name, reason)
+	strategy, err := LookupStrategy(name)
+	if err != nil {
+		return err
+	}
+	return strategy.Update(ctx, s, table, data, keyEdits, fieldSize)
+}
+
+// chooseStrategy is used to get name of registered strategy and the reason it is chosen
+//
+// unjoinable is the reason data can't be updated by join strategies, empty when they can
+func (s *sql) chooseStrategy(rows, fieldSize int, keyEdits []string, unjoinable string) (name, reason string) {
+	switch {
+	case s.audit != nil:
+		return "case", "audit needs rows of every page"
+	case rows == 1:
+		return "sequential", "single row doesn't need bulk query"
+	case rows <= s.workerSize:
+		return "parallel", fmt.Sprintf("%d rows are updated at once by %d workers", rows, s.workerSize)
+	case !s.dialect.SupportsUpdateJoin():
+		return "case", fmt.Sprintf("%s doesn't support UPDATE JOIN", s.dialect)
+	case unjoinable != "":
+		return "case", unjoinable
+	case rows >= autoTempTableRows:
+		return "temp_table", fmt.Sprintf("%d rows are updated by single UPDATE JOIN", rows)
+	case len(keyEdits) > 1:
+		return "values", fmt.Sprintf("%d key edits are repeated for every field by CASE", len(keyEdits))
+	case fieldSize > autoManyFields:
+		return "values", fmt.Sprintf("%d fields make CASE query too long", fieldSize)
+	}
+	return "case", fmt.Sprintf("%d rows with %d fields", rows, fieldSize)
+}
+
+// calibrationCandidates is used to get strategies that can be used by the dialect and the data
+func (s *sql) calibrationCandidates(unjoinable string) []string {
+	if s.audit != nil {
+		return []string{"case"}
+	}
+	if s.dialect.SupportsUpdateJoin() && unjoinable == "" {
+		return []string{"case", "parallel", "values"}
+	}
+	return []string{"case", "parallel"}
+}
+
+// calibrate is used to update equal part of sample using every candidate,
+// so each row is only updated once, then return the fastest candidate
+func (s *sql) calibrate(ctx context.Context, table string, sample []map[string]any, keyEdits []string, fieldSize int, candidates []string) (fastest string, perRow time.Duration, err error) {
+	sampleSize := len(sample) / len(candidates)
+	for index, name := range candidates {
+		strategy, err := LookupStrategy(name)
+		if err != nil {
+			return "", 0, err
+		}
+		startTime := time.Now()
+		if err := strategy.Update(ctx, s, table, sample[index*sampleSize:(index+1)*sampleSize], keyEdits, fieldSize); err != nil {
+			return "", 0, fmt.Errorf("%s: %w", name, err)
+		}
+		elapsed := time.Since(startTime) / time.Duration(sampleSize)
+		s.logger.Printf("UpdateAuto %s calibration %s took %s per row", table, name, elapsed)
+		if fastest == "" || elapsed < perRow {
+			fastest, perRow = name, elapsed
+		}
+	}
+	return fastest, perRow, nil
+}
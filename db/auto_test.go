@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	"go_update_bulk/utils"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordLogger struct {
+	logs []string
+}
+
+func (r *recordLogger) Printf(format string, v ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, v...))
+}
+
+func TestChooseStrategy(t *testing.T) {
+	testCases := []struct {
+		dialect    utils.Dialect
+		rows       int
+		fieldSize  int
+		keyEdits   []string
+		unjoinable string
+		strategy   string
+	}{
+		{dialect: utils.MySQL, rows: 1, fieldSize: 5, keyEdits: []string{"id"}, strategy: "sequential"},
+		{dialect: utils.MySQL, rows: 4, fieldSize: 5, keyEdits: []string{"id"}, strategy: "parallel"},
+		{dialect: utils.Postgres, rows: 20000, fieldSize: 5, keyEdits: []string{"id", "name"}, strategy: "case"},
+		{dialect: utils.MySQL, rows: 20000, fieldSize: 5, keyEdits: []string{"id"}, strategy: "temp_table"},
+		{dialect: utils.MySQL, rows: 100, fieldSize: 5, keyEdits: []string{"id", "name"}, strategy: "values"},
+		{dialect: utils.MySQL, rows: 100, fieldSize: 30, keyEdits: []string{"id"}, strategy: "values"},
+		{dialect: utils.MySQL, rows: 100, fieldSize: 5, keyEdits: []string{"id"}, strategy: "case"},
+		{dialect: utils.MySQL, rows: 20000, fieldSize: 5, keyEdits: []string{"id"}, unjoinable: "expression", strategy: "case"},
+		{dialect: utils.MySQL, rows: 100, fieldSize: 30, keyEdits: []string{"id", "name"}, unjoinable: "different fields", strategy: "case"},
+	}
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+			config, err := newConfig(WithWorkers(4), WithDialect(testCase.dialect))
+			require.Nil(t, err)
+			s := &sql{config: config}
+
+			strategy, reason := s.chooseStrategy(testCase.rows, testCase.fieldSize, testCase.keyEdits, testCase.unjoinable)
+			assert.Equal(t, testCase.strategy, strategy)
+			assert.NotEmpty(t, reason)
+
+			_, err = LookupStrategy(strategy)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestUpdateAuto(t *testing.T) {
+	data := func(size int) []map[string]any {
+		data := []map[string]any{}
+		for i := 1; i <= size; i++ {
+			data = append(data, map[string]any{"id": i, "name": fmt.Sprintf("Name%d", i)})
+		}
+		return data
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		logger := &recordLogger{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithLogger(logger))
+		require.Nil(t, err)
+
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 2, "Name2", 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err = db.UpdateAuto("user", data(2), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []string{"UpdateAuto user chose case: 2 rows with 2 fields"}, logger.logs)
+	})
+
+	t.Run("different fields", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		logger := &recordLogger{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithLogger(logger))
+		require.Nil(t, err)
+
+		mock.ExpectExec("UPDATE `user` SET `age` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `age` END \\), "+
+			"`name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?, \\?\\)").
+			WithArgs(2, 20, 1, "Name1", 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		// many fields would choose values, but unset name of data 2 can't be joined
+		mixed := []map[string]any{
+			{"id": 1, "name": "Name1"},
+			{"id": 2, "name": utils.Optional[string]{}, "age": 20},
+		}
+		err = db.UpdateAuto("user", mixed, []string{"id"}, 30)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []string{"UpdateAuto user chose case: rows with different fields can't be joined"}, logger.logs)
+	})
+
+	t.Run("calibration", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		logger := &recordLogger{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithBatchSize(1), WithCalibration(1), WithLogger(logger))
+		require.Nil(t, err)
+
+		// every candidate updates single row per query, so the number of queries doesn't depend on the fastest one
+		for i := 0; i < 8; i++ {
+			mock.ExpectExec("UPDATE `user`").WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		err = db.UpdateAuto("user", data(8), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+		require.Len(t, logger.logs, 4)
+		assert.Contains(t, logger.logs[3], "fastest calibration")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		err = db.UpdateAuto("", data(2), []string{"id"}, 2)
+		assert.NotNil(t, err)
+
+		err = db.UpdateAuto("user", data(2), []string{}, 2)
+		assert.NotNil(t, err)
+
+		_, err = NewSQLFromStdDB(mockDB, WithCalibration(-1))
+		assert.NotNil(t, err)
+	})
+}
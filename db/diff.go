@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"go_update_bulk/utils"
+	"time"
+)
+
+// updateDiff is used by UpdateBulk when WithDiffOnly is used, each page selects current rows of its keys,
+// then unchanged rows are skipped and only changed fields are put into CASE
+//
+// current rows are locked using SELECT ... FOR UPDATE, so they can't change between the diff and the update
+func (s *sql) updateDiff(ctx context.Context, op OperationEvent, paged [][]map[string]any, keyEdits []string) error {
+	update := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		fields := map[string]bool{}
+		for _, item := range data {
+			for field := range utils.ResolveOptional(item) {
+				fields[field] = true
+			}
+		}
+		selectQuery, selectBinds, err := utils.SelectKeysQuery(s.dialect, op.Table, utils.SortMapKeys(fields), data, keyEdits, true)
+		if err != nil {
+			return fmt.Errorf("failed to build select query %d: %w", pageNumber, err)
+		}
+
+		return s.transaction(ctx, func(tx *sql) error {
+			// supporting query, only the update is the page query
+			selectEvent := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: op.Table}
+			current, err := tx.namedSelectMaps(ctx, selectEvent, selectQuery, selectBinds)
+			if err != nil {
+				return fmt.Errorf("error when select page %d: %w", pageNumber, err)
+			}
+			rows := make(map[string]map[string]any, len(current))
+			for _, row := range current {
+				key, err := utils.RowKey(row, keyEdits)
+				if err != nil {
+					return fmt.Errorf("page %d: %w", pageNumber, err)
+				}
+				rows[key] = row
+			}
+
+			// data without current row would update nothing, so it is skipped as well
+			changed := []map[string]any{}
+			for _, item := range data {
+				key, err := utils.RowKey(item, keyEdits)
+				if err != nil {
+					return fmt.Errorf("page %d: %w", pageNumber, err)
+				}
+				row, ok := rows[key]
+				if !ok {
+					continue
+				}
+				if diff := utils.DiffRow(row, item, keyEdits); diff != nil {
+					changed = append(changed, diff)
+				}
+			}
+			if skipped := len(data) - len(changed); skipped > 0 {
+				s.logger.Printf("%s %s page %d skipped %d unchanged rows", op.Operation, op.Table, pageNumber, skipped)
+			}
+			// skipped rows are processed as well, so the page event counts every data
+			event := QueryEvent{
+				Operation: op.Operation,
+				Strategy:  op.Strategy,
+				Table:     op.Table,
+				Page:      pageNumber,
+				Rows:      len(data),
+				WaitTime:  waitTime,
+			}
+			if len(changed) == 0 {
+				tx.hooks.skip(ctx, &event)
+				return nil
+			}
+
+			query, binds, err := utils.BulkUpdateQuery(tx.dialect, op.Table, changed, keyEdits)
+			if err != nil {
+				return fmt.Errorf("failed to build query %d: %w", pageNumber, err)
+			}
+			query, binds, err = tx.applyGuard(op.Table, query, binds, "AND")
+			if err != nil {
+				return err
+			}
+			event.Placeholders = len(binds)
+			return tx.audited(ctx, op, changed, keyEdits, func() error {
+				if _, err := tx.namedExec(ctx, event, query, binds); err != nil {
+					return fmt.Errorf("error when update page %d: %w", pageNumber, err)
+				}
+				return nil
+			})
+		})
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.runPages(s.auditJob(ctx), paged, update)
+	})
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDiff(t *testing.T) {
+	selectQuery := "SELECT `age`, `id`, `name` FROM `user` WHERE `id` IN \\(\\?, \\?, \\?\\) FOR UPDATE"
+	current := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"age", "id", "name"}).
+			AddRow([]byte("20"), []byte("1"), []byte("Name1")).
+			AddRow([]byte("21"), []byte("2"), []byte("Name2"))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		logger := &recordLogger{}
+		hook := &recordHook{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithDiffOnly(true), WithLogger(logger), WithHooks(hook))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2, 3).WillReturnRows(current())
+		mock.ExpectExec("UPDATE `user` SET `age` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `age` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(2, 22, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		// data 1 is unchanged, data 3 doesn't exist
+		data := []map[string]any{
+			{"id": 1, "name": "Name1", "age": 20},
+			{"id": 2, "name": "Name2", "age": 22},
+			{"id": 3, "name": "Name3", "age": 23},
+		}
+		err = db.UpdateBulk("user", data, []string{"id"}, 3)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []string{"UpdateBulk user page 1 skipped 2 unchanged rows"}, logger.logs)
+
+		// select is supporting query, so only the update is counted as page
+		require.Len(t, hook.after, 2)
+		assert.Equal(t, 0, hook.after[0].Page)
+		assert.Equal(t, 1, hook.after[1].Page)
+		assert.Equal(t, 3, hook.after[1].Rows)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		progress := []Progress{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithDiffOnly(true), WithHooks(NewProgressHook(func(p Progress) {
+			progress = append(progress, p)
+		})))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(selectQuery).WithArgs(1, 2, 3).WillReturnRows(current())
+		mock.ExpectCommit()
+
+		data := []map[string]any{
+			{"id": 1, "name": "Name1", "age": 20},
+			{"id": 2, "name": "Name2", "age": 21},
+			{"id": 3, "name": "Name3", "age": 23},
+		}
+		err = db.UpdateBulk("user", data, []string{"id"}, 3)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// page without update still counts its rows
+		require.NotEmpty(t, progress)
+		last := progress[len(progress)-1]
+		assert.Equal(t, 1, last.PagesDone)
+		assert.Equal(t, 3, last.RowsDone)
+		assert.Equal(t, float64(100), last.Percent())
+	})
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"fmt"
+	"go_update_bulk/utils"
+)
+
+// Where is used to get SQL view that appends guard to WHERE of every update query,
+// so rows in other state are never updated, e.g. Where(map[string]any{"status": "pending", "deleted_at": nil})
+//
+// nil value is compared using IS NULL, upsert strategy doesn't support guard because it may insert
+func (s *sql) Where(guard map[string]any) SQL {
+	view := *s
+	view.guard = guard
+	return &view
+}
+
+// applyGuard is used to append guard of Where to query using joiner,
+// AND when query already has WHERE, otherwise WHERE
+func (s *sql) applyGuard(table, query string, binds map[string]any, joiner string) (string, map[string]any, error) {
+	if len(s.guard) == 0 {
+		return query, binds, nil
+	}
+	guardQuery, guardBinds, err := utils.GuardQuery(s.dialect, table, s.guard)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed build guard: %w", err)
+	}
+
+	merged := make(map[string]any, len(binds)+len(guardBinds))
+	for k, v := range binds {
+		merged[k] = v
+	}
+	for k, v := range guardBinds {
+		merged[k] = v
+	}
+	return fmt.Sprintf("%s %s %s", query, joiner, guardQuery), merged, nil
+}
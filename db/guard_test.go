@@ -0,0 +1,63 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhere(t *testing.T) {
+	data := func() []map[string]any {
+		return []map[string]any{{"id": 1, "name": "Name1"}}
+	}
+	guard := map[string]any{"status": "pending", "deleted_at": nil}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+		require.Nil(t, err)
+		guarded := db.Where(guard)
+
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\) "+
+			"AND `user`.`deleted_at` IS NULL AND `user`.`status` = \\?").
+			WithArgs(1, "Name1", 1, "pending").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\? WHERE `id` = \\? AND `user`.`deleted_at` IS NULL AND `user`.`status` = \\?").
+			WithArgs("Name1", 1, "pending").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user` JOIN \\( VALUES ROW\\(\\?, \\?\\) \\) AS new_values .* WHERE `user`.`deleted_at` IS NULL AND `user`.`status` = \\?").
+			WithArgs(1, "Name1", "pending").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\)$").
+			WithArgs(1, "Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, guarded.UpdateBulk("user", data(), []string{"id"}, 2))
+		assert.Nil(t, guarded.Update("user", map[string]any{"name": "Name1"}, map[string]any{"id": 1}))
+		assert.Nil(t, guarded.UpdateValues("user", data(), []string{"id"}, 2))
+
+		// guard only belongs to the view
+		assert.Nil(t, db.UpdateBulk("user", data(), []string{"id"}, 2))
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+		require.Nil(t, err)
+
+		err = db.Where(guard).UpdateUpsert("user", data(), []string{"id"}, 2)
+		assert.NotNil(t, err)
+
+		err = db.Where(map[string]any{"status; --": 1}).UpdateBulk("user", data(), []string{"id"}, 2)
+		assert.NotNil(t, err)
+	})
+}
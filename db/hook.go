@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// QueryEvent describes a single query executed by SQL
+//
+// Page is 0 when the query is not the main query of a bulk operation page,
+// e.g. single update or supporting query like guard and temporary table
+//
+// Rows is every data of the page, including rows that are skipped, e.g. unchanged rows of WithDiffOnly,
+// Query is empty when every row of the page is skipped
+//
+// WaitTime is how long the page waited for a free worker before the query is executed
+type QueryEvent struct {
+	Operation    string
+	Strategy     string
+	Table        string
+	Page         int
+	Rows         int
+	Placeholders int
+	WaitTime     time.Duration
+	Query        string
+	Binds        any
+	StartTime    time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+}
+
+// Hook is used to observe every query executed by SQL
+//
+// e.g. structured logging, slow query warnings or auditing
+//
+// BeforeQuery may return a derived context, it will be used to execute the query
+// and passed to AfterQuery of the same hook
+type Hook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// OperationEvent describes a single bulk operation, e.g. CreateBulk or UpdateBulk
+type OperationEvent struct {
+	Operation string
+	Strategy  string
+	Table     string
+	Rows      int
+	Pages     int
+	StartTime time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// OperationHook is optionally implemented by Hook to observe bulk operations
+//
+// context returned by BeforeOperation will be used to execute the queries of the operation
+type OperationHook interface {
+	BeforeOperation(ctx context.Context, event *OperationEvent) context.Context
+	AfterOperation(ctx context.Context, event *OperationEvent)
+}
+
+type hooks []Hook
+
+func (h hooks) before(ctx context.Context, event *QueryEvent) (context.Context, []context.Context) {
+	contexts := make([]context.Context, len(h))
+	for index, hook := range h {
+		if next := hook.BeforeQuery(ctx, event); next != nil {
+			ctx = next
+		}
+		contexts[index] = ctx
+	}
+	return ctx, contexts
+}
+
+func (h hooks) after(contexts []context.Context, event *QueryEvent) {
+	for index, hook := range h {
+		hook.AfterQuery(contexts[index], event)
+	}
+}
+
+// run is used to wrap query execution with registered hooks
+func (h hooks) run(ctx context.Context, event *QueryEvent, fn func(ctx context.Context) (int64, error)) error {
+	event.StartTime = time.Now()
+	ctx, contexts := h.before(ctx, event)
+	event.RowsAffected, event.Err = fn(ctx)
+	event.Duration = time.Since(event.StartTime)
+	h.after(contexts, event)
+	return event.Err
+}
+
+// skip is used to notify registered hooks of page that doesn't need query, so its rows are still counted
+func (h hooks) skip(ctx context.Context, event *QueryEvent) {
+	_ = h.run(ctx, event, func(ctx context.Context) (int64, error) {
+		return 0, nil
+	})
+}
+
+// operation is used to wrap bulk operation with registered hooks that implement OperationHook
+func (h hooks) operation(ctx context.Context, event *OperationEvent, fn func(ctx context.Context) error) error {
+	event.StartTime = time.Now()
+	contexts := make([]context.Context, len(h))
+	for index, hook := range h {
+		if hook, ok := hook.(OperationHook); ok {
+			if next := hook.BeforeOperation(ctx, event); next != nil {
+				ctx = next
+			}
+		}
+		contexts[index] = ctx
+	}
+	event.Err = fn(ctx)
+	event.Duration = time.Since(event.StartTime)
+	for index, hook := range h {
+		if hook, ok := hook.(OperationHook); ok {
+			hook.AfterOperation(contexts[index], event)
+		}
+	}
+	return event.Err
+}
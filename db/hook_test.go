@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"go_update_bulk/generator"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordHook struct {
+	mu         sync.Mutex
+	before     []QueryEvent
+	after      []QueryEvent
+	operations []OperationEvent
+}
+
+func (h *recordHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.before = append(h.before, *event)
+	return ctx
+}
+
+func (h *recordHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.after = append(h.after, *event)
+}
+
+func (h *recordHook) BeforeOperation(ctx context.Context, event *OperationEvent) context.Context {
+	return ctx
+}
+
+func (h *recordHook) AfterOperation(ctx context.Context, event *OperationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.operations = append(h.operations, *event)
+}
+
+func TestHook(t *testing.T) {
+	totalData := 10
+	gen := generator.NewGenerator(1001, totalData, generator.NewUserDump(), "db", true)
+	table := gen.Table()
+	primaryKey := gen.Primary()
+
+	hook := &recordHook{}
+	db, err := NewSQL(dataSourceName, WithWorkers(2), WithBatchSize(3), WithHooks(hook))
+	require.Nil(t, err)
+	defer db.Close()
+
+	primaries := []any{}
+	for _, v := range gen.GetCreate() {
+		primaries = append(primaries, v[primaryKey])
+	}
+
+	err = db.CreateBulk(table, gen.GetCreate(), gen.FieldCount())
+	require.Nil(t, err)
+	err = db.UpdateBulk(table, gen.GetUpdate(), []string{primaryKey}, gen.FieldCount())
+	require.Nil(t, err)
+	err = db.Delete(table, map[string]any{primaryKey: primaries})
+	require.Nil(t, err)
+	err = db.Delete(table, map[string]any{"non_exists": 1})
+	require.NotNil(t, err)
+
+	// 1 create, 4 update pages (batch size 3), 2 delete
+	assert.Len(t, hook.before, 7)
+	assert.Len(t, hook.after, 7)
+
+	rows := map[string]int{}
+	for _, event := range hook.after {
+		assert.Equal(t, table, event.Table)
+		assert.NotEmpty(t, event.Query)
+		assert.NotNil(t, event.Binds)
+		assert.False(t, event.StartTime.IsZero())
+		rows[event.Operation] += int(event.RowsAffected)
+	}
+	assert.Equal(t, map[string]int{"CreateBulk": totalData, "UpdateBulk": totalData, "Delete": totalData}, rows)
+
+	require.Len(t, hook.operations, 2)
+	assert.Equal(t, "CreateBulk", hook.operations[0].Operation)
+	assert.Equal(t, 1, hook.operations[0].Pages)
+	assert.Equal(t, "UpdateBulk", hook.operations[1].Operation)
+	assert.Equal(t, "case", hook.operations[1].Strategy)
+	assert.Equal(t, totalData, hook.operations[1].Rows)
+	assert.Equal(t, 4, hook.operations[1].Pages)
+	assert.Nil(t, hook.operations[1].Err)
+
+	failed := hook.after[len(hook.after)-1]
+	assert.Equal(t, "Delete", failed.Operation)
+	assert.NotNil(t, failed.Err)
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var loadDataSequence uint64
+
+func (s *sql) CreateLoadData(table string, data []map[string]any, fieldSize int) error {
+	return s.CreateLoadDataContext(context.Background(), table, data, fieldSize)
+}
+
+// CreateLoadDataContext is used to create huge data by streaming rows as CSV using LOAD DATA LOCAL INFILE
+//
+// rows that have the same fields are sent in single statement without placeholders,
+// so fieldSize doesn't limit the size of the page, server needs local_infile enabled
+func (s *sql) CreateLoadDataContext(ctx context.Context, table string, data []map[string]any, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+	if s.dialect != utils.MySQL {
+		return fmt.Errorf("load data is not supported by %s", s.dialect)
+	}
+
+	data, err := s.checkColumns(ctx, table, data, nil)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	// data with different fields are loaded by different query, so missing field uses default value
+	groups := utils.GroupByFields(data)
+
+	op := OperationEvent{Operation: "CreateLoadData", Strategy: "load_data", Table: table, Rows: len(data), Pages: len(groups)}
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		for index, group := range groups {
+			event := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: table, Page: index + 1, Rows: len(group)}
+			if err := s.loadData(ctx, event, table, utils.SortMapKeys(group[0]), group); err != nil {
+				return fmt.Errorf("error when load data page %d: %w", index+1, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sql) UpdateLoadData(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateLoadDataContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+// UpdateLoadDataContext is used to stream new values into temporary table using LOAD DATA LOCAL INFILE,
+// then update the table by joining the temporary table using keyEdits
+//
+// same as UpdateTempTableContext, but the temporary table is filled without placeholders
+func (s *sql) UpdateLoadDataContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+	if s.dialect != utils.MySQL {
+		return fmt.Errorf("load data is not supported by %s", s.dialect)
+	}
+
+	if err := s.rejectVersion("load_data", table); err != nil {
+		return err
+	}
+	if err := s.rejectAudit("load_data"); err != nil {
+		return err
+	}
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	op := OperationEvent{Operation: "UpdateLoadData", Strategy: "load_data", Table: table, Rows: len(data), Pages: 1}
+
+	fill := func(ctx context.Context, tx *sql, tmpTable string, columns []string) error {
+		event := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: table, Page: 1, Rows: len(data)}
+		if err := tx.loadData(ctx, event, tmpTable, columns, data); err != nil {
+			return fmt.Errorf("error when load temporary data: %w", err)
+		}
+		return nil
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.updateFromTempTable(ctx, op, table, data, keyEdits, fill)
+	})
+}
+
+// loadData is used to stream data into table, every data should have all columns
+//
+// the handler opens new stream every time the driver asks, so retried query sends all rows again
+func (s *sql) loadData(ctx context.Context, event QueryEvent, table string, columns []string, data []map[string]any) error {
+	name := fmt.Sprintf("load_data_%s_%d", strings.ReplaceAll(table, ".", "_"), atomic.AddUint64(&loadDataSequence, 1))
+	query, err := utils.LoadDataQuery(table, name, columns)
+	if err != nil {
+		return fmt.Errorf("failed build query: %w", err)
+	}
+
+	mysql.RegisterReaderHandler(name, func() io.Reader {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(writeLoadData(writer, columns, data, s.loc))
+		}()
+		return reader
+	})
+	defer mysql.DeregisterReaderHandler(name)
+
+	_, err = s.exec(ctx, event, query)
+	return err
+}
+
+// writeLoadData is used to write every row with time in loc, it stops when the reader is closed by the driver
+func writeLoadData(w io.Writer, columns []string, data []map[string]any, loc *time.Location) error {
+	writer := utils.NewLoadDataWriter(w, columns, loc)
+	for index, row := range data {
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("data number %d: %w", index+1, err)
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"go_update_bulk/utils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadData(t *testing.T) {
+	data := []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2"}}
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		err = db.CreateLoadData("", data, 2)
+		assert.NotNil(t, err)
+
+		err = db.CreateLoadData("user", []map[string]any{}, 2)
+		assert.NotNil(t, err)
+
+		err = db.UpdateLoadData("user", data, []string{}, 2)
+		assert.NotNil(t, err)
+
+		err = db.UpdateLoadData("user", data, []string{"id"}, 0)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsupported dialect", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithDialect(utils.Postgres))
+		require.Nil(t, err)
+
+		err = db.CreateLoadData("user", data, 2)
+		assert.NotNil(t, err)
+
+		err = db.UpdateLoadData("user", data, []string{"id"}, 2)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("location", func(t *testing.T) {
+		jakarta := time.FixedZone("WIB", 7*60*60)
+		testCases := []struct {
+			dataSourceName string
+			loc            *time.Location
+			result         string
+		}{
+			{
+				dataSourceName: "root:secret@tcp(localhost:3306)/test",
+				loc:            time.UTC,
+				result:         "1,\"2023-01-02 03:04:05\"\n",
+			},
+			{
+				dataSourceName: "root:secret@tcp(localhost:3306)/test?loc=Asia%2FJakarta",
+				loc:            jakarta,
+				result:         "1,\"2023-01-02 10:04:05\"\n",
+			},
+			{
+				dataSourceName: "invalid",
+				loc:            nil,
+				result:         "1,\"2023-01-02 03:04:05\"\n",
+			},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				loc := dataSourceLocation(testCase.dataSourceName)
+				if testCase.loc == nil {
+					assert.Nil(t, loc)
+				} else {
+					require.NotNil(t, loc)
+					now := time.Now()
+					assert.Equal(t, now.In(testCase.loc).Format(time.RFC3339), now.In(loc).Format(time.RFC3339))
+				}
+
+				var buf bytes.Buffer
+				row := map[string]any{"id": 1, "created_at": time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}
+				err := writeLoadData(&buf, []string{"id", "created_at"}, []map[string]any{row}, loc)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.result, buf.String())
+			})
+		}
+	})
+}
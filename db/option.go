@@ -0,0 +1,292 @@
+package db
+
+import (
+	"errors"
+	"go_update_bulk/utils"
+	"runtime"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Logger is used to print SQL internal information, e.g. retried pages
+//
+// *log.Logger satisfies this interface
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...any) {}
+
+// Option is used to configure SQL on construction
+type Option func(*config)
+
+type config struct {
+	workerSize      int
+	batchSize       int
+	pool            bool
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	dialect         utils.Dialect
+	loc             *time.Location
+	logger          Logger
+	retry           retry
+	hooks           hooks
+	upsertGuard     bool
+	calibration     int
+	columnCheck     ColumnCheck
+	keyCheck        bool
+	diffOnly        bool
+	versions        map[string]version
+	softDeletes     map[string]string
+	audit           AuditSink
+	valueCheck      ValueCheck
+	columnCache     *schemaCache[[]utils.Column]
+	keyCache        *schemaCache[[]Key]
+}
+
+// defaultConfig is number of CPU worker, 100 data per update page and MySQL dialect
+//
+// pool size follows worker size unless WithPool is used
+func defaultConfig() config {
+	return config{
+		workerSize:  runtime.NumCPU(),
+		batchSize:   100,
+		dialect:     utils.MySQL,
+		logger:      nopLogger{},
+		retry:       retry{attempts: 1},
+		upsertGuard: true,
+		columnCache: newSchemaCache[[]utils.Column](),
+		keyCache:    newSchemaCache[[]Key](),
+		versions:    map[string]version{},
+		softDeletes: map[string]string{},
+	}
+}
+
+func newConfig(opts ...Option) (config, error) {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.workerSize <= 0 {
+		return config, errors.New("worker size min 1")
+	}
+	if config.batchSize <= 0 {
+		return config, errors.New("batch size min 1")
+	}
+	if config.calibration < 0 {
+		return config, errors.New("calibration min 0")
+	}
+	if config.columnCheck < ColumnCheckOff || config.columnCheck > ColumnCheckIgnore {
+		return config, errors.New("invalid column check")
+	}
+	if config.valueCheck < ValueCheckOff || config.valueCheck > ValueCheckCoerce {
+		return config, errors.New("invalid value check")
+	}
+	if config.retry.attempts <= 0 {
+		return config, errors.New("retry attempts min 1")
+	}
+	return config, nil
+}
+
+// setPool is used to set connection pool of the database
+//
+// pool follows worker size by default, so workers never open unbounded connections
+func (c config) setPool(db *sqlx.DB) {
+	maxOpenConns := c.maxOpenConns
+	if maxOpenConns <= 0 {
+		maxOpenConns = c.workerSize
+	}
+	maxIdleConns := c.maxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = maxOpenConns
+	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxLifetime(c.connMaxLifetime)
+}
+
+// WithWorkers is used to set how many pages are executed concurrently
+func WithWorkers(workerSize int) Option {
+	return func(c *config) {
+		c.workerSize = workerSize
+	}
+}
+
+// WithBatchSize is used to set maximum data in single bulk update page
+func WithBatchSize(batchSize int) Option {
+	return func(c *config) {
+		c.batchSize = batchSize
+	}
+}
+
+// WithPool is used to set connection pool of the database
+//
+// zero maxOpenConns or maxIdleConns means follow worker size, zero connMaxLifetime means connection is reused forever
+func WithPool(maxOpenConns, maxIdleConns int, connMaxLifetime time.Duration) Option {
+	return func(c *config) {
+		c.pool = true
+		c.maxOpenConns = maxOpenConns
+		c.maxIdleConns = maxIdleConns
+		c.connMaxLifetime = connMaxLifetime
+	}
+}
+
+// WithDialect is used to set SQL dialect, default is MySQL
+//
+// only mysql driver is imported, e.g. postgres needs github.com/lib/pq or pgx stdlib imported by the caller,
+// every query is rebound to the bindVar of the driver
+func WithDialect(dialect utils.Dialect) Option {
+	return func(c *config) {
+		c.dialect = dialect
+	}
+}
+
+// WithLocation is used to set location of time written by LOAD DATA, it should be the same as loc of the connection
+//
+// NewSQL takes it from loc of the mysql data source name, otherwise default is UTC
+func WithLocation(loc *time.Location) Option {
+	return func(c *config) {
+		c.loc = loc
+	}
+}
+
+// WithLogger is used to set logger, default is discard all logs
+func WithLogger(logger Logger) Option {
+	return func(c *config) {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		c.logger = logger
+	}
+}
+
+// WithRetry is used to retry failed page or single update caused by transient error
+//
+// attempts is total execution including the first one, backoff is multiplied by attempt number
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *config) {
+		c.retry = retry{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithHooks is used to register hooks that observe every executed query
+func WithHooks(hooks ...Hook) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, hooks...)
+	}
+}
+
+// WithUpsertGuard is used to check every key of UpdateUpsert page exists before executing it,
+// so missing data is never inserted accidentally, enabled by default
+//
+// guarded page is executed inside transaction and its rows are locked using SELECT ... FOR UPDATE,
+// table that has other primary or unique key than keyEdits is refused
+func WithUpsertGuard(guard bool) Option {
+	return func(c *config) {
+		c.upsertGuard = guard
+	}
+}
+
+// WithCalibration is used to let UpdateAuto measure candidate strategies before choosing one,
+// each candidate updates sampleSize rows of the data, zero means disabled (default)
+func WithCalibration(sampleSize int) Option {
+	return func(c *config) {
+		c.calibration = sampleSize
+	}
+}
+
+// WithColumnCheck is used to compare data fields with columns of the table before any page is executed,
+// so unknown field fails early (ColumnCheckError) or is removed (ColumnCheckIgnore), default is ColumnCheckOff
+//
+// columns are introspected from INFORMATION_SCHEMA once per table and cached
+func WithColumnCheck(check ColumnCheck) Option {
+	return func(c *config) {
+		c.columnCheck = check
+	}
+}
+
+// WithKeyCheck is used to reject keyEdits that don't contain every column of primary or unique key of the table,
+// because such keyEdits may update multiple rows using single data, disabled by default
+//
+// empty keyEdits always default to the primary key of the table
+func WithKeyCheck(check bool) Option {
+	return func(c *config) {
+		c.keyCheck = check
+	}
+}
+
+// WithValueCheck is used to validate every value against nullability, length, numeric range and enum values
+// of its column before any page is executed, default is ValueCheckOff
+//
+// every invalid value is reported in single ValueError, ValueCheckCoerce also converts numeric string of numeric
+// column to number, except decimal column that keeps the exact string, and number of text column to string
+func WithValueCheck(check ValueCheck) Option {
+	return func(c *config) {
+		c.valueCheck = check
+	}
+}
+
+// WithDiffOnly is used to let UpdateBulk select current rows of every page before updating it,
+// unchanged rows are skipped and only changed fields are updated, disabled by default
+//
+// every page is executed inside transaction and its rows are locked using SELECT ... FOR UPDATE
+func WithDiffOnly(diff bool) Option {
+	return func(c *config) {
+		c.diffOnly = diff
+	}
+}
+
+// WithVersion is used to enable optimistic concurrency of the table using integer version column,
+// every data needs the expected version, the row is only updated when its version is still the same,
+// and its version is incremented
+//
+// lost data are reported using ConflictError, data which row doesn't exist or doesn't match Where guard
+// are reported using NotUpdatedError, only case, parallel and sequential strategies support it
+//
+// version takes precedence over WithDiffOnly
+func WithVersion(table, column string) Option {
+	return WithVersionExpr(table, column, utils.Expr{})
+}
+
+// WithVersionExpr is the same as WithVersion, but the new version is set using next expression,
+// e.g. utils.NewExpr("CURRENT_TIMESTAMP(6)", nil) for updated_at column
+func WithVersionExpr(table, column string, next utils.Expr) Option {
+	return func(c *config) {
+		versions := make(map[string]version, len(c.versions)+1)
+		for k, v := range c.versions {
+			versions[k] = v
+		}
+		versions[table] = version{column: column, next: next}
+		c.versions = versions
+	}
+}
+
+// WithSoftDelete is used to mark deleted rows of the table using nullable time column, e.g. deleted_at,
+// Delete, DeleteBulk and EmptyTable set the column instead of removing rows,
+// and Select excludes rows that have the column set
+//
+// use Unscoped to remove rows or to select deleted rows
+func WithSoftDelete(table, column string) Option {
+	return func(c *config) {
+		softDeletes := make(map[string]string, len(c.softDeletes)+1)
+		for k, v := range c.softDeletes {
+			softDeletes[k] = v
+		}
+		softDeletes[table] = column
+		c.softDeletes = softDeletes
+	}
+}
+
+// WithAudit is used to write before and after image of every row changed by bulk update to sink,
+// rows of every page are locked using SELECT ... FOR UPDATE and the page is updated inside transaction
+//
+// only case, values and upsert strategies support it, see ContextWithJobID to tag the entries
+func WithAudit(sink AuditSink) Option {
+	return func(c *config) {
+		c.audit = sink
+	}
+}
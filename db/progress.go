@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Progress is current state of a running bulk operation
+type Progress struct {
+	Operation     string
+	Strategy      string
+	Table         string
+	TotalPages    int
+	PagesDone     int
+	TotalRows     int
+	RowsDone      int
+	Elapsed       time.Duration
+	RowsPerSecond float64
+	ETA           time.Duration
+	Done          bool
+}
+
+// Percent is used to get rows done in percentage
+func (p Progress) Percent() float64 {
+	if p.TotalRows == 0 {
+		return 100
+	}
+	return float64(p.RowsDone) * 100 / float64(p.TotalRows)
+}
+
+// ProgressFunc is called each time a page of bulk operation finished and once the operation is done
+//
+// calls of the same operation are never concurrent
+type ProgressFunc func(progress Progress)
+
+type progressKey struct{}
+
+type progressTracker struct {
+	mu       sync.Mutex
+	progress Progress
+	start    time.Time
+}
+
+func (t *progressTracker) update(fn ProgressFunc, pages, rows int, done bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.PagesDone += pages
+	t.progress.RowsDone += rows
+	t.progress.Done = done
+	t.progress.Elapsed = time.Since(t.start)
+	if seconds := t.progress.Elapsed.Seconds(); seconds > 0 {
+		t.progress.RowsPerSecond = float64(t.progress.RowsDone) / seconds
+	}
+	t.progress.ETA = 0
+	if remaining := t.progress.TotalRows - t.progress.RowsDone; remaining > 0 && t.progress.RowsPerSecond > 0 {
+		t.progress.ETA = time.Duration(float64(remaining) / t.progress.RowsPerSecond * float64(time.Second))
+	}
+	fn(t.progress)
+}
+
+type progressHook struct {
+	fn ProgressFunc
+}
+
+// NewProgressHook is used to report progress of every bulk operation executed by SQL
+//
+// e.g. NewSQL(dataSourceName, WithHooks(NewProgressHook(fn)))
+func NewProgressHook(fn ProgressFunc) Hook {
+	return &progressHook{fn: fn}
+}
+
+func (h *progressHook) BeforeOperation(ctx context.Context, event *OperationEvent) context.Context {
+	tracker := &progressTracker{
+		start: event.StartTime,
+		progress: Progress{
+			Operation:  event.Operation,
+			Strategy:   event.Strategy,
+			Table:      event.Table,
+			TotalPages: event.Pages,
+			TotalRows:  event.Rows,
+		},
+	}
+	return context.WithValue(ctx, progressKey{}, tracker)
+}
+
+func (h *progressHook) AfterOperation(ctx context.Context, event *OperationEvent) {
+	if tracker, ok := ctx.Value(progressKey{}).(*progressTracker); ok {
+		tracker.update(h.fn, 0, 0, true)
+	}
+}
+
+func (h *progressHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *progressHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Page == 0 {
+		return
+	}
+	tracker, ok := ctx.Value(progressKey{}).(*progressTracker)
+	if !ok {
+		return
+	}
+	rows := 0
+	if event.Err == nil {
+		rows = event.Rows
+	}
+	tracker.update(h.fn, 1, rows, false)
+}
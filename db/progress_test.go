@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressHook(t *testing.T) {
+	reports := []Progress{}
+	hook := NewProgressHook(func(progress Progress) {
+		reports = append(reports, progress)
+	})
+	operationHook, ok := hook.(OperationHook)
+	require.True(t, ok)
+
+	op := &OperationEvent{Operation: "UpdateBulk", Strategy: "case", Table: "user", Rows: 10, Pages: 3, StartTime: time.Now().Add(-time.Second)}
+	ctx := operationHook.BeforeOperation(context.Background(), op)
+
+	pages := []*QueryEvent{
+		{Operation: "UpdateBulk", Page: 1, Rows: 4},
+		{Operation: "UpdateBulk", Page: 2, Rows: 4, Err: errors.New("failed")},
+		{Operation: "UpdateBulk", Page: 3, Rows: 2},
+	}
+	for _, page := range pages {
+		queryCtx := hook.BeforeQuery(ctx, page)
+		hook.AfterQuery(queryCtx, page)
+	}
+	operationHook.AfterOperation(ctx, op)
+
+	// query outside of operation is ignored
+	hook.AfterQuery(context.Background(), &QueryEvent{Operation: "Update", Rows: 1})
+
+	require.Len(t, reports, 4)
+
+	first := reports[0]
+	assert.Equal(t, "UpdateBulk", first.Operation)
+	assert.Equal(t, "case", first.Strategy)
+	assert.Equal(t, 1, first.PagesDone)
+	assert.Equal(t, 4, first.RowsDone)
+	assert.Equal(t, float64(40), first.Percent())
+	assert.Greater(t, first.RowsPerSecond, float64(0))
+	assert.Greater(t, first.ETA, time.Duration(0))
+	assert.False(t, first.Done)
+
+	assert.Equal(t, 2, reports[1].PagesDone)
+	assert.Equal(t, 4, reports[1].RowsDone)
+
+	last := reports[3]
+	assert.True(t, last.Done)
+	assert.Equal(t, 3, last.PagesDone)
+	assert.Equal(t, 3, last.TotalPages)
+	assert.Equal(t, 6, last.RowsDone)
+	assert.Equal(t, 10, last.TotalRows)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Transient MySQL error numbers
+const (
+	errLockWaitTimeout = 1205
+	errDeadlock        = 1213
+)
+
+type retry struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// do is used to execute fn until succeed, non retryable error found or run out of attempts
+func (r retry) do(ctx context.Context, logger Logger, name string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= r.attempts || !IsRetryable(err) {
+			return err
+		}
+		logger.Printf("retry %s, attempt %d failed: %v", name, attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(r.backoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// IsRetryable is used to check whether error is transient, e.g. deadlock, lock wait timeout or bad connection
+//
+// mysql.ErrInvalidConn is not retryable, the query may already be executed when the connection breaks,
+// so retry could apply expression or insert page twice, driver.ErrBadConn is only returned before sending it
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+	return errors.Is(err, driver.ErrBadConn)
+}
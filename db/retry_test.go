@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{err: &mysql.MySQLError{Number: 1213}, expected: true},
+		{err: fmt.Errorf("error when update page 1: %w", &mysql.MySQLError{Number: 1205}), expected: true},
+		{err: driver.ErrBadConn, expected: true},
+		{err: mysql.ErrInvalidConn, expected: false},
+		{err: &mysql.MySQLError{Number: 1062}, expected: false},
+		{err: errors.New("failed"), expected: false},
+	}
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+			assert.Equal(t, testCase.expected, IsRetryable(testCase.err))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	deadlock := &mysql.MySQLError{Number: 1213}
+
+	t.Run("success after retry", func(t *testing.T) {
+		calls := 0
+		err := retry{attempts: 3, backoff: time.Millisecond}.do(ctx, nopLogger{}, "test", func() error {
+			calls++
+			if calls < 3 {
+				return deadlock
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("run out of attempts", func(t *testing.T) {
+		calls := 0
+		err := retry{attempts: 2}.do(ctx, nopLogger{}, "test", func() error {
+			calls++
+			return deadlock
+		})
+		assert.Equal(t, deadlock, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		calls := 0
+		err := retry{attempts: 3}.do(ctx, nopLogger{}, "test", func() error {
+			calls++
+			return errors.New("failed")
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ColumnCheck is used to decide what happens to data field that is not a column of the table
+type ColumnCheck int
+
+const (
+	// ColumnCheckOff sends every field to the database as it is (default)
+	ColumnCheckOff ColumnCheck = iota
+	// ColumnCheckError fails the whole operation before any page is executed
+	ColumnCheckError
+	// ColumnCheckIgnore removes unknown fields from the data and logs them
+	ColumnCheckIgnore
+)
+
+// schemaCache is used to keep introspected schema of every table, shared by transaction views
+type schemaCache[T any] struct {
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+func newSchemaCache[T any]() *schemaCache[T] {
+	return &schemaCache[T]{items: map[string]T{}}
+}
+
+func (c *schemaCache[T]) get(table string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	item, ok := c.items[table]
+	return item, ok
+}
+
+func (c *schemaCache[T]) set(table string, item T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[table] = item
+}
+
+// Columns is used to get column names of the table from INFORMATION_SCHEMA, ordered by position
+//
+// columns are cached for the lifetime of SQL, so schema changes need new SQL
+func (s *sql) Columns(ctx context.Context, table string) ([]string, error) {
+	columns, err := s.ColumnTypes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names, nil
+}
+
+// ColumnTypes is the same as Columns, but with type, nullability and size of every column
+func (s *sql) ColumnTypes(ctx context.Context, table string) ([]utils.Column, error) {
+	if table == "" {
+		return nil, errors.New("table is empty")
+	}
+	if columns, ok := s.columnCache.get(table); ok {
+		return columns, nil
+	}
+
+	query, binds, err := utils.ColumnsQuery(s.dialect, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed build query: %w", err)
+	}
+	columns := []utils.Column{}
+	event := QueryEvent{Operation: "Columns", Table: table}
+	if err := s.namedSelect(ctx, event, &columns, query, binds); err != nil {
+		return nil, fmt.Errorf("failed to select columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+
+	s.columnCache.set(table, columns)
+	return columns, nil
+}
+
+// checkColumns is used to validate or strip data fields that are not columns of the table, see WithColumnCheck
+//
+// keyEdits are always validated, stripped data is copied so caller's data is never changed
+func (s *sql) checkColumns(ctx context.Context, table string, data []map[string]any, keyEdits []string) ([]map[string]any, error) {
+	if s.columnCheck == ColumnCheckOff {
+		return data, nil
+	}
+	columns, err := s.Columns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[s.dialect.NormalizeColumn(column)] = true
+	}
+
+	for _, key := range keyEdits {
+		if !known[s.dialect.NormalizeColumn(key)] {
+			return nil, fmt.Errorf("key edit '%s' is not a column of %s", key, table)
+		}
+	}
+
+	result := data
+	ignored := map[string]bool{}
+	for index, item := range data {
+		unknown := []string{}
+		for field := range item {
+			if !known[s.dialect.NormalizeColumn(field)] {
+				unknown = append(unknown, field)
+			}
+		}
+		if len(unknown) == 0 {
+			continue
+		}
+		sort.Strings(unknown)
+		if s.columnCheck == ColumnCheckError {
+			return nil, fmt.Errorf("data %d has unknown column '%s' of %s", index+1, unknown[0], table)
+		}
+
+		if len(ignored) == 0 {
+			result = make([]map[string]any, len(data))
+			copy(result, data)
+		}
+		stripped := make(map[string]any, len(item))
+		for field, value := range item {
+			stripped[field] = value
+		}
+		for _, field := range unknown {
+			delete(stripped, field)
+			ignored[field] = true
+		}
+		result[index] = stripped
+	}
+
+	if len(ignored) > 0 {
+		s.logger.Printf("%s ignored unknown columns %v", table, utils.SortMapKeys(ignored))
+	}
+	return result, nil
+}
+
+// Key is primary or unique key of the table
+type Key struct {
+	Name    string
+	Primary bool
+	Columns []string
+}
+
+// Keys is used to get primary and unique keys of the table from INFORMATION_SCHEMA, primary key is the first
+//
+// keys are cached for the lifetime of SQL, the same as Columns
+func (s *sql) Keys(ctx context.Context, table string) ([]Key, error) {
+	if table == "" {
+		return nil, errors.New("table is empty")
+	}
+	if keys, ok := s.keyCache.get(table); ok {
+		return keys, nil
+	}
+
+	query, binds, err := utils.KeysQuery(s.dialect, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed build query: %w", err)
+	}
+	rows := []struct {
+		KeyName    string `db:"key_name"`
+		KeyType    string `db:"key_type"`
+		ColumnName string `db:"column_name"`
+	}{}
+	event := QueryEvent{Operation: "Keys", Table: table}
+	if err := s.namedSelect(ctx, event, &rows, query, binds); err != nil {
+		return nil, fmt.Errorf("failed to select keys: %w", err)
+	}
+
+	keys := []Key{}
+	for _, row := range rows {
+		if len(keys) == 0 || keys[len(keys)-1].Name != row.KeyName {
+			keys = append(keys, Key{Name: row.KeyName, Primary: row.KeyType == "PRIMARY KEY"})
+		}
+		keys[len(keys)-1].Columns = append(keys[len(keys)-1].Columns, row.ColumnName)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].Primary && !keys[j].Primary
+	})
+
+	s.keyCache.set(table, keys)
+	return keys, nil
+}
+
+// PrimaryKey is used to get columns of the primary key of the table
+func (s *sql) PrimaryKey(ctx context.Context, table string) ([]string, error) {
+	keys, err := s.Keys(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 || !keys[0].Primary {
+		return nil, fmt.Errorf("table %s has no primary key", table)
+	}
+	return keys[0].Columns, nil
+}
+
+// resolveKeyEdits is used to default empty keyEdits to the primary key of the table
+//
+// when WithKeyCheck is used, keyEdits need to contain every column of primary or unique key,
+// otherwise single data may update multiple rows
+func (s *sql) resolveKeyEdits(ctx context.Context, table string, keyEdits []string) ([]string, error) {
+	if len(keyEdits) == 0 {
+		primary, err := s.PrimaryKey(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("key edits is empty: %w", err)
+		}
+		return primary, nil
+	}
+	if !s.keyCheck {
+		return keyEdits, nil
+	}
+
+	keys, err := s.Keys(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	edits := make(map[string]bool, len(keyEdits))
+	for _, key := range keyEdits {
+		edits[s.dialect.NormalizeColumn(key)] = true
+	}
+	for _, key := range keys {
+		backed := true
+		for _, column := range key.Columns {
+			if !edits[s.dialect.NormalizeColumn(column)] {
+				backed = false
+				break
+			}
+		}
+		if backed {
+			return keyEdits, nil
+		}
+	}
+	return nil, fmt.Errorf("key edits %v is not backed by primary or unique key of %s", keyEdits, table)
+}
+
+// ValueCheck is used to decide whether data values are checked against column types
+type ValueCheck int
+
+const (
+	// ValueCheckOff sends every value to the database as it is (default)
+	ValueCheckOff ValueCheck = iota
+	// ValueCheckValidate fails the whole operation when any value is invalid
+	ValueCheckValidate
+	// ValueCheckCoerce is the same as ValueCheckValidate, but convertible value is converted to the column type
+	ValueCheckCoerce
+)
+
+// ValueProblem is invalid value of single data
+type ValueProblem struct {
+	// Data is 1-based index of the data
+	Data   int
+	Column string
+	Err    error
+}
+
+func (p ValueProblem) Error() string {
+	return fmt.Sprintf("data %d column '%s': %v", p.Data, p.Column, p.Err)
+}
+
+// ValueError is returned when some values are invalid for their column, before any query is executed
+type ValueError struct {
+	Table    string
+	Problems []ValueProblem
+}
+
+func (e *ValueError) Error() string {
+	problems := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		problems = append(problems, problem.Error())
+	}
+	return fmt.Sprintf("%d invalid values of %s: %s", len(e.Problems), e.Table, strings.Join(problems, "; "))
+}
+
+// checkValues is used to validate or coerce every value using column types, see WithValueCheck
+//
+// field that is not a column is skipped, coerced data is copied so caller's data is never changed
+func (s *sql) checkValues(ctx context.Context, table string, data []map[string]any) ([]map[string]any, error) {
+	if s.valueCheck == ValueCheckOff {
+		return data, nil
+	}
+	columns, err := s.ColumnTypes(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]utils.Column, len(columns))
+	for _, column := range columns {
+		types[s.dialect.NormalizeColumn(column.Name)] = column
+	}
+
+	coerce := s.valueCheck == ValueCheckCoerce
+	result := data
+	copied := false
+	problems := []ValueProblem{}
+	for index, item := range data {
+		resolved := utils.ResolveOptional(item)
+		var coerced map[string]any
+		for _, field := range utils.SortMapKeys(resolved) {
+			column, ok := types[s.dialect.NormalizeColumn(field)]
+			if !ok {
+				continue
+			}
+			value, err := column.Check(resolved[field], coerce)
+			if err != nil {
+				problems = append(problems, ValueProblem{Data: index + 1, Column: field, Err: err})
+				continue
+			}
+			if !coerce || reflect.DeepEqual(value, resolved[field]) {
+				continue
+			}
+			if coerced == nil {
+				coerced = make(map[string]any, len(resolved))
+				for k, v := range resolved {
+					coerced[k] = v
+				}
+			}
+			coerced[field] = value
+		}
+		if coerced == nil {
+			continue
+		}
+		if !copied {
+			result = make([]map[string]any, len(data))
+			copy(result, data)
+			copied = true
+		}
+		result[index] = coerced
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValueError{Table: table, Problems: problems}
+	}
+	return result, nil
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumnCheck(t *testing.T) {
+	columnsQuery := "SELECT COLUMN_NAME AS column_name, .* FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE\\(\\) AND TABLE_NAME = \\? ORDER BY ORDINAL_POSITION"
+	columns := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("Name")
+	}
+
+	t.Run("error", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithColumnCheck(ColumnCheckError))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(columnsQuery).WithArgs("user").WillReturnRows(columns())
+
+		data := []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2", "nickname": "N"}}
+		err = db.UpdateBulk("user", data, []string{"id"}, 3)
+		assert.EqualError(t, err, "data 2 has unknown column 'nickname' of user")
+
+		// columns are cached, so the next call doesn't query INFORMATION_SCHEMA
+		err = db.UpdateBulk("user", data[:1], []string{"code"}, 2)
+		assert.EqualError(t, err, "key edit 'code' is not a column of user")
+
+		err = db.Update("user", map[string]any{"nickname": "N"}, map[string]any{"id": 1})
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		logger := &recordLogger{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithColumnCheck(ColumnCheckIgnore), WithLogger(logger))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(columnsQuery).WithArgs("user").WillReturnRows(columns())
+		mock.ExpectExec("INSERT INTO `user` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\),\\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 2, "Name2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		data := []map[string]any{{"id": 1, "name": "Name1", "nickname": "N"}, {"id": 2, "name": "Name2"}}
+		err = db.CreateBulk("user", data, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+		assert.Equal(t, []string{"user ignored unknown columns [nickname]"}, logger.logs)
+
+		// caller's data is not changed
+		assert.Equal(t, "N", data[0]["nickname"])
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithColumnCheck(ColumnCheckError))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(columnsQuery).WithArgs("unknown").WillReturnRows(sqlmock.NewRows([]string{"column_name"}))
+		_, err = db.Columns(context.Background(), "unknown")
+		assert.EqualError(t, err, "table unknown not found")
+
+		_, err = NewSQLFromStdDB(mockDB, WithColumnCheck(ColumnCheck(5)))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestKeys(t *testing.T) {
+	keysQuery := "SELECT tc.CONSTRAINT_NAME AS key_name, tc.CONSTRAINT_TYPE AS key_type, kcu.COLUMN_NAME AS column_name FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc"
+	keys := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"key_name", "key_type", "column_name"}).
+			AddRow("code_region", "UNIQUE", "code").
+			AddRow("code_region", "UNIQUE", "region").
+			AddRow("PRIMARY", "PRIMARY KEY", "id")
+	}
+
+	t.Run("default primary key", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(keysQuery).WithArgs("user").WillReturnRows(keys())
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(1, "Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = db.UpdateBulk("user", []map[string]any{{"id": 1, "name": "Name1"}}, nil, 2)
+		assert.Nil(t, err)
+
+		result, err := db.Keys(context.Background(), "user")
+		assert.Nil(t, err)
+		assert.Equal(t, []Key{
+			{Name: "PRIMARY", Primary: true, Columns: []string{"id"}},
+			{Name: "code_region", Columns: []string{"code", "region"}},
+		}, result)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key check", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithKeyCheck(true))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(keysQuery).WithArgs("user").WillReturnRows(keys())
+		mock.ExpectExec("UPDATE `user` SET `name` = \\? WHERE `code` = \\? AND `region` = \\? AND `sku` = \\?").
+			WithArgs("Name1", "A", "ID", "S").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		data := []map[string]any{{"code": "A", "region": "ID", "sku": "S", "name": "Name1"}}
+		err = db.UpdateSequential("user", data, []string{"code"}, 4)
+		assert.EqualError(t, err, "key edits [code] is not backed by primary or unique key of user")
+
+		err = db.UpdateSequential("user", data, []string{"code", "region", "sku"}, 4)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectQuery(keysQuery).WithArgs("log").
+			WillReturnRows(sqlmock.NewRows([]string{"key_name", "key_type", "column_name"}).AddRow("code", "UNIQUE", "code"))
+		_, err = db.PrimaryKey(context.Background(), "log")
+		assert.EqualError(t, err, "table log has no primary key")
+	})
+}
+
+func TestValueCheck(t *testing.T) {
+	columnsQuery := "SELECT COLUMN_NAME AS column_name, .* FROM INFORMATION_SCHEMA.COLUMNS"
+	columns := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"column_name", "data_type", "column_type", "is_nullable", "character_maximum_length", "numeric_precision", "numeric_scale"}).
+			AddRow("id", "int", "int(11)", "NO", nil, 10, 0).
+			AddRow("name", "varchar", "varchar(5)", "NO", 5, nil, nil).
+			AddRow("age", "tinyint", "tinyint(3) unsigned", "YES", nil, 3, 0)
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithValueCheck(ValueCheckValidate))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(columnsQuery).WithArgs("user").WillReturnRows(columns())
+
+		data := []map[string]any{
+			{"id": 1, "name": "Name1", "age": 300},
+			{"id": 2, "name": "Name12", "age": nil},
+			{"id": 3, "name": nil, "age": "ten"},
+		}
+		err = db.UpdateBulk("user", data, []string{"id"}, 3)
+		valueErr := &ValueError{}
+		require.ErrorAs(t, err, &valueErr)
+		assert.Equal(t, "user", valueErr.Table)
+		assert.Equal(t, []string{
+			"data 1 column 'age': 300 is out of range [0, 255]",
+			"data 2 column 'name': length 6 is more than 5",
+			"data 3 column 'age': 'ten' is not an integer",
+			"data 3 column 'name': null is not allowed",
+		}, problemMessages(valueErr.Problems))
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("coerce", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithValueCheck(ValueCheckCoerce))
+		require.Nil(t, err)
+
+		mock.ExpectQuery(columnsQuery).WithArgs("user").WillReturnRows(columns())
+		mock.ExpectExec("UPDATE `user` SET `age` = \\?, `name` = \\? WHERE `id` = \\?").
+			WithArgs(int64(20), "123", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		data := []map[string]any{{"id": 1, "name": 123, "age": "20"}}
+		err = db.UpdateSequential("user", data, []string{"id"}, 3)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		_, err = NewSQLFromStdDB(mockDB, WithValueCheck(ValueCheck(-1)))
+		assert.NotNil(t, err)
+	})
+}
+
+func problemMessages(problems []ValueProblem) []string {
+	messages := []string{}
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	return messages
+}
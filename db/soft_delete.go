@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"time"
+)
+
+// Unscoped is used to get SQL view that ignores WithSoftDelete, so Delete, DeleteBulk and EmptyTable
+// remove rows and Select includes deleted rows
+func (s *sql) Unscoped() SQL {
+	view := *s
+	view.unscoped = true
+	return &view
+}
+
+// softDeleteOf is used to get soft delete column of the table, none when the view is unscoped
+func (s *sql) softDeleteOf(table string) (string, bool) {
+	if s.unscoped {
+		return "", false
+	}
+	column, ok := s.softDeletes[table]
+	return column, ok
+}
+
+// softDeleted is used to add condition that excludes deleted rows to condition of Select,
+// condition that already has the column is kept, so deleted rows can still be selected explicitly
+func (s *sql) softDeleted(table string, condition *map[string]any) *map[string]any {
+	column, ok := s.softDeleteOf(table)
+	if !ok {
+		return condition
+	}
+	scoped := map[string]any{}
+	if condition != nil {
+		if _, ok := (*condition)[column]; ok {
+			return condition
+		}
+		for k, v := range *condition {
+			scoped[k] = v
+		}
+	}
+	scoped[column] = nil
+	return &scoped
+}
+
+// softDeleteQuery is used to build query that marks rows matching condition as deleted,
+// rows that are already deleted keep their deleted time
+func (s *sql) softDeleteQuery(table, column string, condition map[string]any) (string, map[string]any, error) {
+	where := make(map[string]any, len(condition)+1)
+	for k, v := range condition {
+		where[k] = v
+	}
+	where[column] = nil
+	payload := map[string]any{column: utils.NewExpr("CURRENT_TIMESTAMP", nil)}
+	return utils.UpdateQuery(s.dialect, table, payload, where)
+}
+
+func (s *sql) DeleteBulk(table string, data []map[string]any, keyEdits []string) error {
+	return s.DeleteBulkContext(context.Background(), table, data, keyEdits)
+}
+
+// DeleteBulkContext is used to delete rows which keys exist in data using single query for every page,
+// fields other than keyEdits are ignored, empty keyEdits uses primary key of the table
+//
+// soft delete table only marks rows that are not deleted yet
+func (s *sql) DeleteBulkContext(ctx context.Context, table string, data []map[string]any, keyEdits []string) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]map[string]any, 0, len(data))
+	for _, item := range data {
+		keys = append(keys, utils.PickFields(item, keyEdits))
+	}
+	keys, err = s.checkColumns(ctx, table, keys, keyEdits)
+	if err != nil {
+		return err
+	}
+
+	strategy := "delete"
+	build := func(data []map[string]any) (string, map[string]any, error) {
+		return utils.DeleteKeysQuery(s.dialect, table, data, keyEdits)
+	}
+	if column, ok := s.softDeleteOf(table); ok {
+		strategy = "soft"
+		build = func(data []map[string]any) (string, map[string]any, error) {
+			return utils.SoftDeleteKeysQuery(s.dialect, table, column, data, keyEdits)
+		}
+	}
+
+	paged := utils.PagedData(keys, s.updatePageSize(len(keys), len(keys)*len(keyEdits)))
+	remove := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		query, binds, err := build(data)
+		if err != nil {
+			return fmt.Errorf("failed to build query %d: %w", pageNumber, err)
+		}
+		event := QueryEvent{
+			Operation:    "DeleteBulk",
+			Strategy:     strategy,
+			Table:        table,
+			Page:         pageNumber,
+			Rows:         len(data),
+			Placeholders: len(binds),
+			WaitTime:     waitTime,
+		}
+		if _, err := s.namedExec(ctx, event, query, binds); err != nil {
+			return fmt.Errorf("error when delete page %d: %w", pageNumber, err)
+		}
+		return nil
+	}
+
+	op := OperationEvent{Operation: "DeleteBulk", Strategy: strategy, Table: table, Rows: len(keys), Pages: len(paged)}
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.runPages(ctx, paged, remove)
+	})
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithSoftDelete("user", "deleted_at"))
+		require.Nil(t, err)
+
+		mock.ExpectExec("UPDATE `user` SET `deleted_at` = CURRENT_TIMESTAMP WHERE `deleted_at` IS NULL AND `id` = \\?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user` SET `deleted_at` = CURRENT_TIMESTAMP WHERE `id` IN \\(\\?, \\?\\) AND `deleted_at` IS NULL").
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("SELECT `id`, `name` FROM `user` WHERE `deleted_at` IS NULL AND `id` IN \\(\\?, \\?\\)").
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Name1"))
+		mock.ExpectQuery("SELECT `id` FROM `user` WHERE `deleted_at` IS NULL$").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec("UPDATE `user` SET `deleted_at` = CURRENT_TIMESTAMP WHERE `deleted_at` IS NULL$").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `product` SET `name` = \\? WHERE `id` = \\?").
+			WithArgs("Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, db.Delete("user", map[string]any{"id": 1}))
+		assert.Nil(t, db.DeleteBulk("user", []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2}}, []string{"id"}))
+
+		dest := []struct {
+			ID   int    `db:"id"`
+			Name string `db:"name"`
+		}{}
+		assert.Nil(t, db.Select(&dest, "user", []string{"id", "name"}, &map[string]any{"id": []any{1, 2}}, nil))
+		assert.Len(t, dest, 1)
+		ids := []int{}
+		assert.Nil(t, db.Select(&ids, "user", []string{"id"}, nil, nil))
+		assert.Nil(t, db.EmptyTable("user"))
+
+		// other table is not affected
+		assert.Nil(t, db.Update("product", map[string]any{"name": "Name1"}, map[string]any{"id": 1}))
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unscoped", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithSoftDelete("user", "deleted_at"))
+		require.Nil(t, err)
+		unscoped := db.Unscoped()
+
+		mock.ExpectExec("DELETE FROM `user` WHERE `id` = \\?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM `user` WHERE \\(`code`, `id`\\) IN \\(\\(\\?, \\?\\)\\)").
+			WithArgs("A", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT `id` FROM `user`$").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT `id` FROM `user` WHERE `deleted_at` = \\?").
+			WithArgs("2023-01-02").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec("DELETE FROM `user`$").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, unscoped.Delete("user", map[string]any{"id": 1}))
+		assert.Nil(t, unscoped.DeleteBulk("user", []map[string]any{{"id": 1, "code": "A"}}, []string{"id", "code"}))
+		ids := []int{}
+		assert.Nil(t, unscoped.Select(&ids, "user", []string{"id"}, nil, nil))
+		// condition of the column selects deleted rows explicitly
+		assert.Nil(t, db.Select(&ids, "user", []string{"id"}, &map[string]any{"deleted_at": "2023-01-02"}, nil))
+		assert.Nil(t, unscoped.EmptyTable("user"))
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithSoftDelete("user", "deleted_at`; --"))
+		require.Nil(t, err)
+
+		assert.NotNil(t, db.DeleteBulk("", []map[string]any{{"id": 1}}, []string{"id"}))
+		assert.NotNil(t, db.DeleteBulk("user", []map[string]any{}, []string{"id"}))
+		assert.NotNil(t, db.DeleteBulk("user", []map[string]any{{"name": "Name1"}}, []string{"id"}))
+		assert.NotNil(t, db.Delete("user", map[string]any{"id": 1}))
+	})
+}
@@ -2,11 +2,14 @@ package db
 
 import (
 	"context"
+	stdsql "database/sql"
 	"errors"
 	"fmt"
 	"go_update_bulk/utils"
+	"reflect"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"golang.org/x/sync/semaphore"
 )
@@ -14,41 +17,154 @@ import (
 type SQL interface {
 	DB() *sqlx.DB
 	CreateBulk(table string, data []map[string]any, fieldSize int) error
+	CreateBulkContext(ctx context.Context, table string, data []map[string]any, fieldSize int) error
+	CreateLoadData(table string, data []map[string]any, fieldSize int) error
+	CreateLoadDataContext(ctx context.Context, table string, data []map[string]any, fieldSize int) error
 	UpdateBulk(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateBulkContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
 	UpdateParallel(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateParallelContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
 	UpdateSequential(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateSequentialContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateValues(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateValuesContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateUpsert(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateUpsertContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateTempTable(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateTempTableContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateLoadData(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateLoadDataContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateAuto(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+	UpdateAutoContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error
 	Update(table string, data, condition map[string]any) error
 	Delete(table string, condition map[string]any) error
+	DeleteBulk(table string, data []map[string]any, keyEdits []string) error
+	DeleteBulkContext(ctx context.Context, table string, data []map[string]any, keyEdits []string) error
 	Select(dest any, table string, fields []string, condition *map[string]any, paginate *utils.Paginate) error
 	EmptyTable(table string) error
+	Columns(ctx context.Context, table string) ([]string, error)
+	ColumnTypes(ctx context.Context, table string) ([]utils.Column, error)
+	Keys(ctx context.Context, table string) ([]Key, error)
+	PrimaryKey(ctx context.Context, table string) ([]string, error)
+	WithTx(tx *sqlx.Tx) SQL
+	Where(guard map[string]any) SQL
+	Unscoped() SQL
+	Transaction(ctx context.Context, fn func(tx SQL) error) error
 	Close() error
 }
 
 type sql struct {
-	db         *sqlx.DB
-	workerSize int
-	batchSize  int
+	db    *sqlx.DB
+	ext   sqlx.ExtContext
+	tx    *sqlx.Tx
+	owned bool
+	// guard is appended to every update query, see Where
+	guard map[string]any
+	// unscoped ignores soft delete of tables, see Unscoped
+	unscoped bool
+	config
 }
 
-func NewSQL(dataSourceName string, workerSize, batchSize int) (SQL, error) {
+// NewSQL is used to connect database and configure SQL using options
+//
+// e.g. NewSQL(dataSourceName, WithWorkers(4), WithBatchSize(200))
+func NewSQL(dataSourceName string, opts ...Option) (SQL, error) {
 	if dataSourceName == "" {
 		return nil, errors.New("data source name is empty")
 	}
-	if workerSize <= 0 {
-		return nil, errors.New("worker size min 1")
+	config, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
 	}
-	if batchSize <= 0 {
-		return nil, errors.New("batch size min 1")
+
+	if config.loc == nil && config.dialect == utils.MySQL {
+		config.loc = dataSourceLocation(dataSourceName)
 	}
 
-	db, err := sqlx.Connect("mysql", dataSourceName)
+	db, err := sqlx.Connect(config.dialect.DriverName(), dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("failed connect database: %w", err)
 	}
+	config.setPool(db)
+
+	sql := sql{
+		db:     db,
+		ext:    db,
+		owned:  true,
+		config: config,
+	}
+	return &sql, nil
+}
+
+// dataSourceLocation is used to get loc of mysql data source name, nil when it can't be parsed
+func dataSourceLocation(dataSourceName string) *time.Location {
+	dsn, err := mysql.ParseDSN(dataSourceName)
+	if err != nil {
+		return nil
+	}
+	return dsn.Loc
+}
+
+// NewSQLFromDB is used to wrap existing database, so SQL shares the caller's pool
+//
+// pool is only configured when WithPool is used, Close will not close the database
+func NewSQLFromDB(db *sqlx.DB, opts ...Option) (SQL, error) {
+	if db == nil {
+		return nil, errors.New("database is nil")
+	}
+	config, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if config.pool {
+		config.setPool(db)
+	}
 	sql := sql{
-		db:         db,
-		workerSize: workerSize,
-		batchSize:  batchSize,
+		db:     db,
+		ext:    db,
+		config: config,
+	}
+	return &sql, nil
+}
+
+// NewSQLFromStdDB is used to wrap existing database/sql database, e.g. opened using sqlmock or other driver
+//
+// driver name is taken from WithDialect
+func NewSQLFromStdDB(db *stdsql.DB, opts ...Option) (SQL, error) {
+	if db == nil {
+		return nil, errors.New("database is nil")
+	}
+	config, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLFromDB(sqlx.NewDb(db, config.dialect.DriverName()), opts...)
+}
+
+// NewSQLFromExt is used to execute queries using existing executor, e.g. caller's transaction
+//
+// *sqlx.Tx can't be used concurrently and can't be retried after failed statement,
+// so pages are executed one by one without retry
+//
+// *sqlx.DB is the same as NewSQLFromDB, so operations that need transaction can begin it,
+// otherwise DB will return nil and Close won't do anything
+func NewSQLFromExt(ext sqlx.ExtContext, opts ...Option) (SQL, error) {
+	if ext == nil {
+		return nil, errors.New("executor is nil")
+	}
+	if db, ok := ext.(*sqlx.DB); ok {
+		return NewSQLFromDB(db, opts...)
+	}
+	config, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	sql := sql{
+		ext:    ext,
+		config: config,
+	}
+	if tx, ok := ext.(*sqlx.Tx); ok {
+		return sql.WithTx(tx), nil
 	}
 	return &sql, nil
 }
@@ -58,6 +174,10 @@ func (s *sql) DB() *sqlx.DB {
 }
 
 func (s *sql) CreateBulk(table string, data []map[string]any, fieldSize int) error {
+	return s.CreateBulkContext(context.Background(), table, data, fieldSize)
+}
+
+func (s *sql) CreateBulkContext(ctx context.Context, table string, data []map[string]any, fieldSize int) error {
 	if table == "" {
 		return errors.New("table is empty")
 	}
@@ -67,207 +187,300 @@ func (s *sql) CreateBulk(table string, data []map[string]any, fieldSize int) err
 	if fieldSize <= 0 {
 		return errors.New("field size minimum 1")
 	}
-	query, _, err := utils.CreateQuery(table, data[0])
+
+	data, err := s.checkColumns(ctx, table, data, nil)
 	if err != nil {
-		return fmt.Errorf("failed build query %w", err)
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	sem := semaphore.NewWeighted(int64(s.workerSize))
-
-	size := len(data)
-	pageSize := utils.BulkMaxDataSize(size, fieldSize*size)
-	paged := utils.PagedData(data, pageSize)
-
-	errors := make(chan error, len(paged))
-
-	create := func(pageNumber int, data []map[string]any) error {
-		if _, err = s.db.NamedExec(query, data); err != nil {
-			return fmt.Errorf("error when create page %d: %w", pageNumber, err)
+	// data with different fields are inserted by different query
+	paged := [][]map[string]any{}
+	queries := []string{}
+	placeholders := []int{}
+	for _, group := range utils.GroupByFields(data) {
+		query, binds, err := utils.CreateQuery(s.dialect, table, group[0])
+		if err != nil {
+			return fmt.Errorf("failed build query %w", err)
+		}
+		totalField := fieldSize
+		if len(binds) > totalField {
+			totalField = len(binds)
+		}
+		size := len(group)
+		pageSize := utils.BulkMaxDataSize(size, totalField*size)
+		for _, page := range utils.PagedData(group, pageSize) {
+			paged = append(paged, page)
+			queries = append(queries, query)
+			placeholders = append(placeholders, len(page)*len(binds))
 		}
-		return nil
 	}
 
-	for index, page := range paged {
-		pageNumber := index + 1
-		if err := sem.Acquire(ctx, 1); err != nil {
-			return fmt.Errorf("error acquire semaphore on page %d: %w", pageNumber, err)
+	create := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		event := QueryEvent{
+			Operation:    "CreateBulk",
+			Strategy:     "insert",
+			Table:        table,
+			Page:         pageNumber,
+			Rows:         len(data),
+			Placeholders: placeholders[pageNumber-1],
+			WaitTime:     waitTime,
 		}
-		go func(pageNumber int, data []map[string]any) {
-			defer sem.Release(1)
-			if err := create(pageNumber, data); err != nil {
-				errors <- err
-			}
-		}(pageNumber, page)
-	}
-	if err := sem.Acquire(ctx, int64(s.workerSize)); err != nil {
-		return fmt.Errorf("error wait semaphore: %w", err)
+		if _, err := s.namedExec(ctx, event, queries[pageNumber-1], data); err != nil {
+			return fmt.Errorf("error when create page %d: %w", pageNumber, err)
+		}
+		return nil
 	}
 
-	close(errors)
-	for err := range errors {
-		return err
-	}
-	return nil
+	op := OperationEvent{Operation: "CreateBulk", Strategy: "insert", Table: table, Rows: len(data), Pages: len(paged)}
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.runPages(ctx, paged, create)
+	})
 }
 
 func (s *sql) UpdateBulk(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateBulkContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+func (s *sql) UpdateBulkContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
 	if table == "" {
 		return errors.New("table is empty")
 	}
 	if len(data) == 0 {
 		return errors.New("data is empty")
 	}
-	if len(keyEdits) == 0 {
-		return errors.New("key edits is empty")
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
 	}
 	if fieldSize <= 0 {
 		return errors.New("field size minimum 1")
 	}
 
-	ctx := context.Background()
-	sem := semaphore.NewWeighted(int64(s.workerSize))
-	size := len(data)
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
 
+	// data may have more fields than fieldSize, estimate should never be less than the real placeholders
+	if maxFieldSize := utils.MaxFieldSize(data); maxFieldSize > fieldSize {
+		fieldSize = maxFieldSize
+	}
 	totalField := utils.BulkUpdateEstimateTotalField(len(data), fieldSize, len(keyEdits))
+	paged := utils.PagedData(data, s.updatePageSize(len(data), totalField))
+
+	op := OperationEvent{Operation: "UpdateBulk", Strategy: "case", Table: table, Rows: len(data), Pages: len(paged)}
+	if v, ok := s.versionOf(table); ok {
+		return s.updateVersioned(ctx, op, paged, keyEdits, v)
+	}
+	if s.diffOnly {
+		return s.updateDiff(ctx, op, paged, keyEdits)
+	}
+	return s.bulkUpdate(ctx, op, paged, keyEdits, func(data []map[string]any) (string, map[string]any, error) {
+		query, binds, err := utils.BulkUpdateQuery(s.dialect, table, data, keyEdits)
+		if err != nil {
+			return "", nil, err
+		}
+		return s.applyGuard(table, query, binds, "AND")
+	})
+}
 
+// updatePageSize is batch size, or less when the placeholders of a batch exceed maximum placeholder
+func (s *sql) updatePageSize(dataSize, totalField int) int {
 	pageSize := s.batchSize
-	calculatedPageSize := utils.BulkMaxDataSize(size, totalField)
+	calculatedPageSize := utils.BulkMaxDataSize(dataSize, totalField)
 	if calculatedPageSize < pageSize {
 		pageSize = calculatedPageSize
 	}
+	return pageSize
+}
 
-	paged := utils.PagedData(data, pageSize)
-	errors := make(chan error, len(paged))
-
-	update := func(pageNumber int, data []map[string]any, keyEdit []string) error {
-		query, binds, err := utils.BulkUpdateQuery(table, data, keyEdit)
+// bulkUpdate is used to execute every page using single query that built by build function
+//
+// when WithAudit is used every page is executed inside transaction, so its rows can be audited
+func (s *sql) bulkUpdate(ctx context.Context, op OperationEvent, paged [][]map[string]any, keyEdits []string, build func(data []map[string]any) (string, map[string]any, error)) error {
+	update := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		query, binds, err := build(data)
 		if err != nil {
 			return fmt.Errorf("failed to build query %d: %w", pageNumber, err)
 		}
-		if _, err := s.db.NamedExec(query, binds); err != nil {
-			return fmt.Errorf("error when update page %d: %w", pageNumber, err)
-		}
-		return nil
-	}
-
-	for index, page := range paged {
-		pageNumber := index + 1
-		if err := sem.Acquire(ctx, 1); err != nil {
-			return fmt.Errorf("error acquire semaphore on page %d: %w", pageNumber, err)
+		event := QueryEvent{
+			Operation:    op.Operation,
+			Strategy:     op.Strategy,
+			Table:        op.Table,
+			Page:         pageNumber,
+			Rows:         len(data),
+			Placeholders: len(binds),
+			WaitTime:     waitTime,
 		}
-		go func(pageNumber int, data []map[string]any) {
-			defer sem.Release(1)
-			if err := update(pageNumber, data, keyEdits); err != nil {
-				errors <- err
+		if s.audit == nil {
+			if _, err := s.namedExec(ctx, event, query, binds); err != nil {
+				return fmt.Errorf("error when update page %d: %w", pageNumber, err)
 			}
-		}(pageNumber, page)
-	}
-	if err := sem.Acquire(ctx, int64(s.workerSize)); err != nil {
-		return fmt.Errorf("error wait semaphore: %w", err)
-	}
-
-	close(errors)
-	for err := range errors {
-		return err
-	}
-
-	return nil
+			return nil
+		}
+		return s.transaction(ctx, func(tx *sql) error {
+			return tx.audited(ctx, op, data, keyEdits, func() error {
+				if _, err := tx.namedExec(ctx, event, query, binds); err != nil {
+					return fmt.Errorf("error when update page %d: %w", pageNumber, err)
+				}
+				return nil
+			})
+		})
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.runPages(s.auditJob(ctx), paged, update)
+	})
 }
 
 func (s *sql) UpdateParallel(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateParallelContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+func (s *sql) UpdateParallelContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
 	if table == "" {
 		return errors.New("table is empty")
 	}
 	if len(data) == 0 {
 		return errors.New("data is empty")
 	}
-	if len(keyEdits) == 0 {
-		return errors.New("key edits is empty")
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
 	}
 	if fieldSize <= 0 {
 		return errors.New("field size minimum 1")
 	}
 
-	ctx := context.Background()
-	sem := semaphore.NewWeighted(int64(s.workerSize))
-	errors := make(chan error, len(data))
-
-	update := func(dataNumber int, data map[string]any) error {
-		condition := map[string]any{}
-		for _, key := range keyEdits {
-			value, ok := data[key]
-			if !ok {
-				return fmt.Errorf("data %d not have '%s' property", dataNumber, key)
-			}
-			condition[key] = value
-			delete(data, key)
-		}
-		if err := s.Update(table, data, condition); err != nil {
-			return fmt.Errorf("error when update page %d: %w", dataNumber, err)
-		}
-		return nil
+	if err := s.rejectAudit("parallel"); err != nil {
+		return err
 	}
-
-	for index, item := range data {
-		if err := sem.Acquire(ctx, 1); err != nil {
-			return fmt.Errorf("error acquire semaphore: %w", err)
-		}
-		go func(dataNumber int, data map[string]any) {
-			defer sem.Release(1)
-			if err := update(dataNumber, data); err != nil {
-				errors <- err
-			}
-		}(index+1, item)
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
 	}
-	if err := sem.Acquire(ctx, int64(s.workerSize)); err != nil {
-		return fmt.Errorf("error wait semaphore: %w", err)
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
 	}
 
-	close(errors)
-	for err := range errors {
-		return err
+	// Each page only contains single data
+	paged := utils.PagedData(data, 1)
+
+	// data that lost version conflict doesn't stop other data
+	lost := &conflicts{}
+	update := func(ctx context.Context, dataNumber int, data []map[string]any, waitTime time.Duration) error {
+		event := QueryEvent{Operation: "UpdateParallel", Strategy: "parallel", Table: table, Page: dataNumber, Rows: 1, WaitTime: waitTime}
+		return lost.collect(s.updateSingle(ctx, event, table, dataNumber, data[0], keyEdits))
 	}
 
-	return nil
+	op := OperationEvent{Operation: "UpdateParallel", Strategy: "parallel", Table: table, Rows: len(data), Pages: len(paged)}
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		if err := s.runPages(ctx, paged, update); err != nil {
+			return err
+		}
+		return lost.err(table)
+	})
 }
 
 func (s *sql) UpdateSequential(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateSequentialContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+func (s *sql) UpdateSequentialContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
 	if table == "" {
 		return errors.New("table is empty")
 	}
 	if len(data) == 0 {
 		return errors.New("data is empty")
 	}
-	if len(keyEdits) == 0 {
-		return errors.New("key edits is empty")
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
 	}
 	if fieldSize <= 0 {
 		return errors.New("field size minimum 1")
 	}
 
-	update := func(dataNumber int, data map[string]any) error {
-		condition := map[string]any{}
-		for _, key := range keyEdits {
-			value, ok := data[key]
-			if !ok {
-				return fmt.Errorf("data %d not have '%s' property", dataNumber, key)
+	if err := s.rejectAudit("sequential"); err != nil {
+		return err
+	}
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	op := OperationEvent{Operation: "UpdateSequential", Strategy: "sequential", Table: table, Rows: len(data), Pages: len(data)}
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		lost := &conflicts{}
+		for index, item := range data {
+			event := QueryEvent{Operation: "UpdateSequential", Strategy: "sequential", Table: table, Page: index + 1, Rows: 1}
+			if err := lost.collect(s.updateSingle(ctx, event, table, index+1, item, keyEdits)); err != nil {
+				return err
 			}
-			condition[key] = value
-			delete(data, key)
 		}
-		if err := s.Update(table, data, condition); err != nil {
-			return fmt.Errorf("error when update page %d: %w", dataNumber, err)
+		return lost.err(table)
+	})
+}
+
+// updateSingle is used by UpdateParallel and UpdateSequential to update single data using keyEdits as condition
+func (s *sql) updateSingle(ctx context.Context, event QueryEvent, table string, dataNumber int, data map[string]any, keyEdits []string) error {
+	condition := map[string]any{}
+	for _, key := range keyEdits {
+		value, ok := data[key]
+		if !ok {
+			return fmt.Errorf("data %d not have '%s' property", dataNumber, key)
 		}
-		return nil
+		condition[key] = value
+		delete(data, key)
 	}
+	if err := s.update(ctx, event, table, data, condition); err != nil {
+		return fmt.Errorf("error when update page %d: %w", dataNumber, err)
+	}
+	return nil
+}
 
-	for index, item := range data {
-		if err := update(index+1, item); err != nil {
-			return err
+// runPages is used to execute each page concurrently limited by worker size
+//
+// wait until all started pages finished and return the first error found
+func (s *sql) runPages(ctx context.Context, paged [][]map[string]any, fn func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error) error {
+	sem := semaphore.NewWeighted(int64(s.workerSize))
+	errors := make(chan error, len(paged))
+
+	for index, page := range paged {
+		pageNumber := index + 1
+		waitStart := time.Now()
+		if err := sem.Acquire(ctx, 1); err != nil {
+			errors <- fmt.Errorf("error acquire semaphore on page %d: %w", pageNumber, err)
+			break
 		}
+		go func(pageNumber int, data []map[string]any, waitTime time.Duration) {
+			defer sem.Release(1)
+			if err := fn(ctx, pageNumber, data, waitTime); err != nil {
+				errors <- err
+			}
+		}(pageNumber, page, time.Since(waitStart))
+	}
+	if err := sem.Acquire(context.Background(), int64(s.workerSize)); err != nil {
+		return fmt.Errorf("error wait semaphore: %w", err)
 	}
 
+	close(errors)
+	for err := range errors {
+		return err
+	}
 	return nil
 }
 
@@ -282,10 +495,41 @@ func (s *sql) Update(table string, data, condition map[string]any) error {
 		return errors.New("condition is empty")
 	}
 
-	query, binds, err := utils.UpdateQuery(table, data, condition)
+	ctx := context.Background()
+	checked, err := s.checkColumns(ctx, table, []map[string]any{data}, utils.SortMapKeys(condition))
+	if err != nil {
+		return err
+	}
+	checked, err = s.checkValues(ctx, table, checked)
+	if err != nil {
+		return err
+	}
+
+	event := QueryEvent{Operation: "Update", Table: table, Rows: 1}
+	return s.update(ctx, event, table, checked[0], condition)
+}
+
+// update is used by Update, UpdateParallel and UpdateSequential to update single data
+//
+// when the table has version column, ConflictError is returned if the expected version doesn't match,
+// NotUpdatedError if the row doesn't exist or doesn't match Where guard
+func (s *sql) update(ctx context.Context, event QueryEvent, table string, data, condition map[string]any) error {
+	v, versioned := s.versionOf(table)
+	if versioned {
+		var err error
+		if data, condition, err = s.versionedUpdate(v, data, condition); err != nil {
+			return err
+		}
+	}
+
+	query, binds, err := utils.UpdateQuery(s.dialect, table, data, condition)
 	if err != nil {
 		return fmt.Errorf("failed build query: %w", err)
 	}
+	query, binds, err = s.applyGuard(table, query, binds, "AND")
+	if err != nil {
+		return err
+	}
 	query, args, err := sqlx.Named(query, binds)
 	if err != nil {
 		return fmt.Errorf("failed bind named: %w", err)
@@ -296,10 +540,14 @@ func (s *sql) Update(table string, data, condition map[string]any) error {
 		return fmt.Errorf("failed bindVar: %w", err)
 	}
 
-	_, err = s.db.Exec(query, args...)
+	event.Placeholders = len(args)
+	rowsAffected, err := s.exec(ctx, event, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed update: %w", err)
 	}
+	if versioned && rowsAffected == 0 {
+		return s.versionMiss(ctx, event, table, v, condition)
+	}
 
 	return nil
 }
@@ -312,7 +560,12 @@ func (s *sql) Delete(table string, condition map[string]any) error {
 		return errors.New("condition is empty")
 	}
 
-	query, binds, err := utils.DeleteQuery(table, condition)
+	event := QueryEvent{Operation: "Delete", Strategy: "delete", Table: table}
+	query, binds, err := utils.DeleteQuery(s.dialect, table, condition)
+	if column, ok := s.softDeleteOf(table); ok {
+		event.Strategy = "soft"
+		query, binds, err = s.softDeleteQuery(table, column, condition)
+	}
 	if err != nil {
 		return fmt.Errorf("failed build query: %w", err)
 	}
@@ -326,7 +579,7 @@ func (s *sql) Delete(table string, condition map[string]any) error {
 		return fmt.Errorf("failed bindVar: %w", err)
 	}
 
-	_, err = s.db.Exec(query, args...)
+	_, err = s.exec(context.Background(), event, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed delete: %w", err)
 	}
@@ -342,7 +595,7 @@ func (s *sql) Select(dest any, table string, fields []string, condition *map[str
 		return errors.New("fields is empty")
 	}
 
-	query, bind, err := utils.SelectQuery(table, fields, condition, paginate)
+	query, bind, err := utils.SelectQuery(s.dialect, table, fields, s.softDeleted(table, condition), paginate)
 	if err != nil {
 		return fmt.Errorf("failed to build query: %w", err)
 	}
@@ -356,7 +609,8 @@ func (s *sql) Select(dest any, table string, fields []string, condition *map[str
 		return fmt.Errorf("failed bindVar: %w", err)
 	}
 
-	if err := s.db.Select(dest, query, args...); err != nil {
+	event := QueryEvent{Operation: "Select", Table: table}
+	if err := s.selectContext(context.Background(), event, dest, query, args...); err != nil {
 		return fmt.Errorf("failed to select data: %w", err)
 	}
 
@@ -367,13 +621,138 @@ func (s *sql) EmptyTable(table string) error {
 	if table == "" {
 		return errors.New("table is empty")
 	}
-	query := fmt.Sprintf("DELETE FROM %s", table)
-	if _, err := s.db.Exec(query); err != nil {
+	if column, ok := s.softDeleteOf(table); ok {
+		query, binds, err := s.softDeleteQuery(table, column, map[string]any{})
+		if err != nil {
+			return err
+		}
+		event := QueryEvent{Operation: "EmptyTable", Strategy: "soft", Table: table}
+		_, err = s.namedExec(context.Background(), event, query, binds)
+		return err
+	}
+
+	quoted, err := s.dialect.QuoteTable(table)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("DELETE FROM %s", quoted)
+	event := QueryEvent{Operation: "EmptyTable", Table: table}
+	if _, err := s.exec(context.Background(), event, query); err != nil {
 		return err
 	}
 	return nil
 }
 
+// Close is used to close the database, only when it is opened by NewSQL
 func (s *sql) Close() error {
+	if !s.owned {
+		return nil
+	}
 	return s.db.Close()
 }
+
+// namedExec is used to execute named query and notify registered hooks
+//
+// transient error is retried using the same query, hooks only notified once with the last result
+func (s *sql) namedExec(ctx context.Context, event QueryEvent, query string, arg any) (int64, error) {
+	event.Query = query
+	event.Binds = arg
+	err := s.hooks.run(ctx, &event, func(ctx context.Context) (rowsAffected int64, err error) {
+		err = s.retry.do(ctx, s.logger, event.Operation, func() error {
+			result, err := sqlx.NamedExecContext(ctx, s.ext, query, arg)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err = result.RowsAffected()
+			return err
+		})
+		return rowsAffected, err
+	})
+	return event.RowsAffected, err
+}
+
+// exec is used to execute query and notify registered hooks
+//
+// ? bindVar of query is rebound to the bindVar of the driver, e.g. $1 for postgres
+//
+// transient error is retried using the same query, hooks only notified once with the last result
+func (s *sql) exec(ctx context.Context, event QueryEvent, query string, args ...any) (int64, error) {
+	query = s.ext.Rebind(query)
+	event.Query = query
+	event.Binds = args
+	err := s.hooks.run(ctx, &event, func(ctx context.Context) (rowsAffected int64, err error) {
+		err = s.retry.do(ctx, s.logger, event.Operation, func() error {
+			result, err := s.ext.ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			rowsAffected, err = result.RowsAffected()
+			return err
+		})
+		return rowsAffected, err
+	})
+	return event.RowsAffected, err
+}
+
+// namedSelect is used to select rows using named query into dest and notify registered hooks
+func (s *sql) namedSelect(ctx context.Context, event QueryEvent, dest any, query string, binds map[string]any) error {
+	query, args, err := sqlx.Named(query, binds)
+	if err != nil {
+		return fmt.Errorf("failed bind named: %w", err)
+	}
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed bindVar: %w", err)
+	}
+	event.Placeholders = len(args)
+	return s.selectContext(ctx, event, dest, query, args...)
+}
+
+// namedSelectMaps is the same as namedSelect, but every row is scanned into map of column name and value
+func (s *sql) namedSelectMaps(ctx context.Context, event QueryEvent, query string, binds map[string]any) ([]map[string]any, error) {
+	query, args, err := sqlx.Named(query, binds)
+	if err != nil {
+		return nil, fmt.Errorf("failed bind named: %w", err)
+	}
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed bindVar: %w", err)
+	}
+	query = s.ext.Rebind(query)
+
+	result := []map[string]any{}
+	event.Query = query
+	event.Binds = args
+	event.Placeholders = len(args)
+	err = s.hooks.run(ctx, &event, func(ctx context.Context) (int64, error) {
+		rows, err := s.ext.QueryxContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			row := map[string]any{}
+			if err := rows.MapScan(row); err != nil {
+				return 0, err
+			}
+			result = append(result, row)
+		}
+		return int64(len(result)), rows.Err()
+	})
+	return result, err
+}
+
+// selectContext is used to select rows into dest and notify registered hooks
+//
+// ? bindVar of query is rebound the same as exec, RowsAffected of the event is the number of selected rows
+func (s *sql) selectContext(ctx context.Context, event QueryEvent, dest any, query string, args ...any) error {
+	query = s.ext.Rebind(query)
+	event.Query = query
+	event.Binds = args
+	return s.hooks.run(ctx, &event, func(ctx context.Context) (int64, error) {
+		if err := sqlx.SelectContext(ctx, s.ext, dest, query, args...); err != nil {
+			return 0, err
+		}
+		return int64(reflect.Indirect(reflect.ValueOf(dest)).Len()), nil
+	})
+}
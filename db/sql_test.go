@@ -1,13 +1,18 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
 	"go_update_bulk/generator"
 	"go_update_bulk/utils"
+	"log"
 	"math"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,32 +22,64 @@ func toString(data any) string {
 	return string(bytes)
 }
 
+func withoutKeys(data []map[string]any, keys []string) []map[string]any {
+	result := make([]map[string]any, 0, len(data))
+	for _, item := range data {
+		m := map[string]any{}
+		for k, v := range item {
+			if !contains(keys, k) {
+				m[k] = v
+			}
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
 // Make sure data source name exists
 const dataSourceName = "root:root@(localhost:3307)/test_db?parseTime=true"
 
 func TestNewSQL(t *testing.T) {
 	t.Run("failed", func(t *testing.T) {
-		_, err := NewSQL("", 1, 1)
+		_, err := NewSQL("", WithWorkers(1), WithBatchSize(1))
 		assert.NotNil(t, err)
 
-		_, err = NewSQL("non_exists:non_exists@(non_exists:404)/non_exists", 1, 1)
+		_, err = NewSQL("non_exists:non_exists@(non_exists:404)/non_exists", WithWorkers(1), WithBatchSize(1))
 		assert.NotNil(t, err)
 
-		_, err = NewSQL(dataSourceName, 0, 1)
+		_, err = NewSQL(dataSourceName, WithWorkers(0), WithBatchSize(1))
 		assert.NotNil(t, err)
 
-		_, err = NewSQL(dataSourceName, 1, 0)
+		_, err = NewSQL(dataSourceName, WithWorkers(1), WithBatchSize(0))
+		assert.NotNil(t, err)
+
+		_, err = NewSQL(dataSourceName, WithRetry(0, time.Millisecond))
 		assert.NotNil(t, err)
 	})
 
 	t.Run("success", func(t *testing.T) {
-		_, err := NewSQL(dataSourceName, 1, 1)
+		_, err := NewSQL(dataSourceName, WithWorkers(1), WithBatchSize(1))
 		assert.Nil(t, err)
+
+		db, err := NewSQL(dataSourceName)
+		require.Nil(t, err)
+		assert.Equal(t, runtime.NumCPU(), db.DB().Stats().MaxOpenConnections)
+
+		db, err = NewSQL(
+			dataSourceName,
+			WithWorkers(4),
+			WithPool(8, 2, time.Minute),
+			WithDialect(utils.MySQL),
+			WithLogger(log.Default()),
+			WithRetry(3, time.Millisecond),
+		)
+		require.Nil(t, err)
+		assert.Equal(t, 8, db.DB().Stats().MaxOpenConnections)
 	})
 }
 
 func TestDbSQL(t *testing.T) {
-	totalData := 10
+	totalData := 24
 	removeNil := true
 	tag := "db"
 
@@ -72,7 +109,7 @@ func TestDbSQL(t *testing.T) {
 	}
 
 	// Init db
-	db, err := NewSQL(dataSourceName, runtime.NumCPU(), 200)
+	db, err := NewSQL(dataSourceName, WithWorkers(runtime.NumCPU()), WithBatchSize(200))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -82,6 +119,9 @@ func TestDbSQL(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	diffDB, err := NewSQLFromDB(db.DB(), WithDiffOnly(true))
+	require.Nil(t, err)
+
 	t.Run("create", func(t *testing.T) {
 
 		t.Run("failed", func(t *testing.T) {
@@ -109,6 +149,21 @@ func TestDbSQL(t *testing.T) {
 		})
 	})
 
+	t.Run("schema", func(t *testing.T) {
+		primary, err := db.PrimaryKey(context.Background(), table)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{primaryKey}, primary)
+
+		columns, err := db.Columns(context.Background(), table)
+		assert.Nil(t, err)
+		assert.Contains(t, columns, primaryKey)
+
+		types, err := db.ColumnTypes(context.Background(), table)
+		assert.Nil(t, err)
+		assert.Len(t, types, len(columns))
+		assert.NotEmpty(t, types[0].DataType)
+	})
+
 	t.Run("select", func(t *testing.T) {
 		data := []Type{}
 		t.Run("failed", func(t *testing.T) {
@@ -132,11 +187,19 @@ func TestDbSQL(t *testing.T) {
 		functions := []struct {
 			name string
 			fn   func(table string, data []map[string]any, keyEdits []string, fieldSize int) error
+			// tempTable is true when the strategy needs temporary table
+			tempTable bool
 		}{
 			{name: "bulk", fn: db.UpdateBulk},
 			{name: "sequential", fn: db.UpdateSequential},
 			{name: "parallel", fn: db.UpdateParallel},
+			{name: "temp_table", fn: db.UpdateTempTable, tempTable: true},
+			{name: "values", fn: db.UpdateValues},
+			{name: "upsert", fn: db.UpdateUpsert},
+			{name: "load_data", fn: db.UpdateLoadData, tempTable: true},
+			{name: "diff", fn: diffDB.UpdateBulk},
 		}
+		tempTable := supportsTemporaryTable(t, db, table)
 
 		updateFnCount := len(functions)
 
@@ -162,7 +225,8 @@ func TestDbSQL(t *testing.T) {
 					err = item.fn(table, []map[string]any{}, keyEdit, fieldSize)
 					assert.NotNil(t, err)
 
-					err = item.fn(table, data, []string{}, fieldSize)
+					// empty key edits default to primary key, unknown table has no primary key
+					err = item.fn("non_exists", data, []string{}, fieldSize)
 					assert.NotNil(t, err)
 
 					err = item.fn(table, data, []string{"non_exists"}, fieldSize)
@@ -173,6 +237,12 @@ func TestDbSQL(t *testing.T) {
 				})
 
 				t.Run("success", func(t *testing.T) {
+					if item.tempTable && !tempTable {
+						t.Skip("database does not support temporary table")
+					}
+					// some strategies remove key edits from data, others not
+					expected := withoutKeys(data, keyEdit)
+
 					err := item.fn(table, data, keyEdit, fieldSize)
 					assert.Nil(t, err)
 
@@ -181,7 +251,7 @@ func TestDbSQL(t *testing.T) {
 					mapped, _ := utils.StructsToMaps(dest, tag, removeNil)
 					require.Nil(t, err)
 					assert.Len(t, dest, len(data))
-					assert.Equal(t, toString(data), toString(mapped))
+					assert.Equal(t, toString(expected), toString(mapped))
 				})
 			})
 		}
@@ -209,3 +279,191 @@ func TestDbSQL(t *testing.T) {
 		})
 	})
 }
+
+func TestNewSQLFrom(t *testing.T) {
+	t.Run("failed", func(t *testing.T) {
+		_, err := NewSQLFromDB(nil)
+		assert.NotNil(t, err)
+
+		_, err = NewSQLFromStdDB(nil)
+		assert.NotNil(t, err)
+
+		_, err = NewSQLFromExt(nil)
+		assert.NotNil(t, err)
+
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		_, err = NewSQLFromStdDB(mockDB, WithWorkers(0))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("database", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+		require.Nil(t, err)
+		assert.NotNil(t, db.DB())
+
+		mock.ExpectExec("INSERT INTO `user` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\),\\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 2, "Name2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		data := []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2"}}
+		err = db.CreateBulk("user", data, 2)
+		assert.Nil(t, err)
+
+		// Not owned, so the database is not closed
+		assert.Nil(t, db.Close())
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("different fields", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+		require.Nil(t, err)
+
+		mock.ExpectExec("INSERT INTO `user` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\),\\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 3, "Name3").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO `user` \\(`id`\\) VALUES \\(\\?\\)").
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// nil name removed by StructToMap
+		data := []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2}, {"id": 3, "name": "Name3"}}
+		err = db.CreateBulk("user", data, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		err = db.UpdateTempTable("user", data, []string{"id"}, 2)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("executor database", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromExt(sqlx.NewDb(mockDB, "mysql"), WithWorkers(1), WithDiffOnly(true))
+		require.Nil(t, err)
+		assert.NotNil(t, db.DB())
+
+		// diff only needs transaction of the database
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT `id`, `name` FROM `user` WHERE `id` IN \\(\\?\\) FOR UPDATE").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("1"), []byte("Name1")))
+		mock.ExpectCommit()
+
+		err = db.UpdateBulkContext(context.Background(), "user", []map[string]any{{"id": 1, "name": "Name1"}}, []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `user` SET `name` = \\? WHERE `id` = \\?").
+			WithArgs("Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := sqlx.NewDb(mockDB, "mysql").Beginx()
+		require.Nil(t, err)
+
+		db, err := NewSQLFromExt(tx, WithWorkers(4))
+		require.Nil(t, err)
+		assert.Nil(t, db.DB())
+
+		err = db.Update("user", map[string]any{"name": "Name1"}, map[string]any{"id": 1})
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Commit())
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresBindVar(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer mockDB.Close()
+
+	db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithDialect(utils.Postgres))
+	require.Nil(t, err)
+
+	mock.ExpectExec(`UPDATE "user" SET "name" = \$1 WHERE "id" = \$2`).
+		WithArgs("Name1", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "user" WHERE "id" IN \(\$1, \$2\)`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`SELECT "id" FROM "user" WHERE "id" = \$1 LIMIT \$2 OFFSET \$3`).
+		WithArgs(1, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	err = db.Update("user", map[string]any{"name": "Name1"}, map[string]any{"id": 1})
+	assert.Nil(t, err)
+	err = db.Delete("user", map[string]any{"id": []int{1, 2}})
+	assert.Nil(t, err)
+	ids := []int{}
+	err = db.Select(&ids, "user", []string{"id"}, &map[string]any{"id": 1}, &utils.Paginate{Page: 1, Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1}, ids)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateExpression(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.Nil(t, err)
+	defer mockDB.Close()
+
+	db, err := NewSQLFromStdDB(mockDB, WithWorkers(1))
+	require.Nil(t, err)
+
+	mock.ExpectExec("UPDATE `product` SET `stock` = \\( CASE WHEN `id` = \\? THEN stock - \\? WHEN `id` = \\? THEN stock - \\? ELSE `stock` END \\) WHERE `id` IN \\(\\?, \\?\\)").
+		WithArgs(1, 2, 2, 5, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `product` SET `stock` = stock - \\? WHERE `id` = \\?").
+		WithArgs(1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	decrement := func(n int) utils.Expr {
+		return utils.NewExpr("stock - :n", map[string]any{"n": n})
+	}
+	data := []map[string]any{{"id": 1, "stock": decrement(2)}, {"id": 2, "stock": decrement(5)}}
+	err = db.UpdateBulk("product", data, []string{"id"}, 2)
+	assert.Nil(t, err)
+
+	err = db.UpdateSequential("product", []map[string]any{{"id": 3, "stock": decrement(1)}}, []string{"id"}, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	err = db.UpdateTempTable("product", data, []string{"id"}, 2)
+	assert.NotNil(t, err)
+}
+
+// supportsTemporaryTable is used to check whether the test database can create temporary table,
+// e.g. some MySQL compatible servers can't
+func supportsTemporaryTable(t *testing.T, db SQL, table string) bool {
+	query, err := utils.CreateTemporaryTableQuery(utils.MySQL, "tmp_probe", table, []string{"id"})
+	require.Nil(t, err)
+
+	// temporary table only exists in the connection that creates it
+	conn, err := db.DB().Connx(context.Background())
+	require.Nil(t, err)
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), query); err != nil {
+		t.Logf("temporary table is not supported: %v", err)
+		return false
+	}
+	_, err = conn.ExecContext(context.Background(), "DROP TEMPORARY TABLE IF EXISTS `tmp_probe`")
+	require.Nil(t, err)
+	return true
+}
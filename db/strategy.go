@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// UpdateStrategy is used to bulk update data of the table using SQL
+//
+// Name is used to select the strategy from registry, see RegisterStrategy
+type UpdateStrategy interface {
+	Name() string
+	Update(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+}
+
+// UpdateFunc is signature of the function that bulk update data of the table
+type UpdateFunc func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error
+
+type updateStrategy struct {
+	name string
+	fn   UpdateFunc
+}
+
+// NewUpdateStrategy is used to create UpdateStrategy from function
+func NewUpdateStrategy(name string, fn UpdateFunc) UpdateStrategy {
+	return updateStrategy{name: name, fn: fn}
+}
+
+func (u updateStrategy) Name() string {
+	return u.name
+}
+
+func (u updateStrategy) Update(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return u.fn(ctx, sql, table, data, keyEdits, fieldSize)
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]UpdateStrategy{}
+)
+
+func init() {
+	for _, strategy := range []UpdateStrategy{
+		NewUpdateStrategy("case", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateBulkContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("parallel", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateParallelContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("sequential", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateSequentialContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("values", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateValuesContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("upsert", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateUpsertContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("temp_table", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateTempTableContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("load_data", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateLoadDataContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+		NewUpdateStrategy("auto", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			return sql.UpdateAutoContext(ctx, table, data, keyEdits, fieldSize)
+		}),
+	} {
+		if err := RegisterStrategy(strategy); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterStrategy is used to make strategy selectable by its name
+//
+// built-in strategies use the same name as Strategy of the events: case, parallel, sequential,
+// values, upsert, temp_table and load_data, auto chooses one of them, see UpdateAutoContext
+func RegisterStrategy(strategy UpdateStrategy) error {
+	if strategy == nil {
+		return errors.New("strategy is nil")
+	}
+	name := strategy.Name()
+	if name == "" {
+		return errors.New("strategy name is empty")
+	}
+
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	if _, ok := strategies[name]; ok {
+		return fmt.Errorf("strategy %s already registered", name)
+	}
+	strategies[name] = strategy
+	return nil
+}
+
+// LookupStrategy is used to get registered strategy by its name
+func LookupStrategy(name string) (UpdateStrategy, error) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	strategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown update strategy %s", name)
+	}
+	return strategy, nil
+}
+
+// Strategies is used to get sorted names of registered strategies
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rejectDialect is used by strategies that only build MySQL query, e.g. UPDATE ... JOIN or ON DUPLICATE KEY UPDATE,
+// so other dialect fails before any query is sent
+func (s *sql) rejectDialect(strategy string) error {
+	if !s.dialect.SupportsUpdateJoin() {
+		return fmt.Errorf("%s is not supported by %s, use case, parallel or sequential", strategy, s.dialect)
+	}
+	return nil
+}
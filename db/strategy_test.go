@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"go_update_bulk/utils"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrategy(t *testing.T) {
+	ctx := context.Background()
+	data := []map[string]any{{"id": 1, "name": "Name1"}}
+
+	t.Run("lookup", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(1, "Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		strategy, err := LookupStrategy("case")
+		require.Nil(t, err)
+		assert.Equal(t, "case", strategy.Name())
+
+		err = strategy.Update(ctx, db, "user", data, []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		assert.Subset(t, Strategies(), []string{"auto", "case", "load_data", "parallel", "sequential", "temp_table", "upsert", "values"})
+	})
+
+	t.Run("register", func(t *testing.T) {
+		called := false
+		strategy := NewUpdateStrategy("test_custom", func(ctx context.Context, sql SQL, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+			called = true
+			return nil
+		})
+		require.Nil(t, RegisterStrategy(strategy))
+
+		found, err := LookupStrategy("test_custom")
+		require.Nil(t, err)
+		assert.Nil(t, found.Update(ctx, nil, "user", data, []string{"id"}, 2))
+		assert.True(t, called)
+		assert.Contains(t, Strategies(), "test_custom")
+	})
+
+	t.Run("unsupported dialect", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithDialect(utils.Postgres))
+		require.Nil(t, err)
+
+		// MySQL only query must not reach the server
+		for index, name := range []string{"values", "temp_table", "upsert"} {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				strategy, err := LookupStrategy(name)
+				require.Nil(t, err)
+				err = strategy.Update(ctx, db, "user", data, []string{"id"}, 2)
+				assert.EqualError(t, err, fmt.Sprintf("%s is not supported by postgres, use case, parallel or sequential", name))
+			})
+		}
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, err := LookupStrategy("non_exists")
+		assert.NotNil(t, err)
+
+		err = RegisterStrategy(nil)
+		assert.NotNil(t, err)
+
+		err = RegisterStrategy(NewUpdateStrategy("", nil))
+		assert.NotNil(t, err)
+
+		err = RegisterStrategy(NewUpdateStrategy("case", nil))
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var tmpTableSequence uint64
+
+func (s *sql) UpdateTempTable(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateTempTableContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+// UpdateTempTableContext is used to bulk insert new values into temporary table,
+// then update the table by joining the temporary table using keyEdits
+//
+// temporary table only exists in single connection, so everything is executed inside a transaction
+func (s *sql) UpdateTempTableContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+
+	if err := s.rejectDialect("temp_table"); err != nil {
+		return err
+	}
+	if err := s.rejectVersion("temp_table", table); err != nil {
+		return err
+	}
+	if err := s.rejectAudit("temp_table"); err != nil {
+		return err
+	}
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	// every row binds all fields of the first data, the same as the insert query of fill
+	size := len(data)
+	pageSize := utils.BulkMaxDataSize(size, len(data[0])*size)
+	paged := utils.PagedData(data, pageSize)
+
+	op := OperationEvent{Operation: "UpdateTempTable", Strategy: "temp_table", Table: table, Rows: size, Pages: len(paged)}
+
+	fill := func(ctx context.Context, tx *sql, tmpTable string, columns []string) error {
+		insertQuery, binds, err := utils.CreateQuery(tx.dialect, tmpTable, data[0])
+		if err != nil {
+			return fmt.Errorf("failed build query: %w", err)
+		}
+		insert := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+			event := QueryEvent{
+				Operation:    op.Operation,
+				Strategy:     op.Strategy,
+				Table:        table,
+				Page:         pageNumber,
+				Rows:         len(data),
+				Placeholders: len(data) * len(binds),
+				WaitTime:     waitTime,
+			}
+			if _, err := tx.namedExec(ctx, event, insertQuery, data); err != nil {
+				return fmt.Errorf("error when insert temporary page %d: %w", pageNumber, err)
+			}
+			return nil
+		}
+		return tx.runPages(ctx, paged, insert)
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.updateFromTempTable(ctx, op, table, data, keyEdits, fill)
+	})
+}
+
+// updateFromTempTable is used to create temporary table like table, fill it with new values,
+// then update the table by joining the temporary table using keyEdits
+func (s *sql) updateFromTempTable(
+	ctx context.Context,
+	op OperationEvent,
+	table string,
+	data []map[string]any,
+	keyEdits []string,
+	fill func(ctx context.Context, tx *sql, tmpTable string, columns []string) error,
+) error {
+	// temporary table is created in the current schema, so schema qualified table is flattened
+	tmpTable := fmt.Sprintf("tmp_%s_%d", strings.ReplaceAll(table, ".", "_"), atomic.AddUint64(&tmpTableSequence, 1))
+
+	resolved := utils.ResolveOptionals(data)
+	columns := utils.SortMapKeys(resolved[0])
+	fields := []string{}
+	for _, column := range columns {
+		if !contains(keyEdits, column) {
+			fields = append(fields, column)
+		}
+	}
+	if len(fields)+len(keyEdits) != len(columns) {
+		return fmt.Errorf("data 1 not have all key edits %v", keyEdits)
+	}
+	// missing field would be NULL in temporary table and overwrite the current value
+	for index, item := range resolved {
+		if len(item) != len(columns) {
+			return fmt.Errorf("fields of data number %d is different with the first data", index+1)
+		}
+		if field, ok := utils.HasExpr(item); ok {
+			return fmt.Errorf("expression '%s' of the data number %d is not supported", field, index+1)
+		}
+		for _, column := range columns {
+			if _, ok := item[column]; !ok {
+				return fmt.Errorf("key '%s' not found in the data number %d", column, index+1)
+			}
+		}
+	}
+
+	createTmpQuery, err := utils.CreateTemporaryTableQuery(s.dialect, tmpTable, table, columns)
+	if err != nil {
+		return fmt.Errorf("failed build query: %w", err)
+	}
+	keyTmpQuery, err := utils.AddPrimaryKeyQuery(s.dialect, tmpTable, keyEdits)
+	if err != nil {
+		return fmt.Errorf("failed build query: %w", err)
+	}
+	updateQuery, err := utils.UpdateJoinQuery(s.dialect, table, tmpTable, fields, keyEdits)
+	if err != nil {
+		return fmt.Errorf("failed build query: %w", err)
+	}
+	updateQuery, updateBinds, err := s.applyGuard(table, updateQuery, map[string]any{}, "WHERE")
+	if err != nil {
+		return err
+	}
+	quotedTmpTable, err := s.dialect.QuoteTable(tmpTable)
+	if err != nil {
+		return fmt.Errorf("failed build query: %w", err)
+	}
+	dropTmpQuery := fmt.Sprintf("DROP TEMPORARY TABLE IF EXISTS %s", quotedTmpTable)
+
+	event := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: table}
+
+	return s.transaction(ctx, func(tx *sql) (err error) {
+		if _, err := tx.exec(ctx, event, createTmpQuery); err != nil {
+			return fmt.Errorf("failed create temporary table: %w", err)
+		}
+		defer func() {
+			if _, dropErr := tx.exec(ctx, event, dropTmpQuery); dropErr != nil && err == nil {
+				err = fmt.Errorf("failed drop temporary table: %w", dropErr)
+			}
+		}()
+
+		if _, err := tx.exec(ctx, event, keyTmpQuery); err != nil {
+			return fmt.Errorf("failed add key of temporary table: %w", err)
+		}
+
+		if err := fill(ctx, tx, tmpTable, columns); err != nil {
+			return err
+		}
+
+		if _, err := tx.namedExec(ctx, event, updateQuery, updateBinds); err != nil {
+			return fmt.Errorf("error when update from temporary table: %w", err)
+		}
+		return nil
+	})
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateTempTable(t *testing.T) {
+	data := func() []map[string]any {
+		return []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2"}}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMPORARY TABLE `tmp_user_[0-9]+` AS SELECT `id`, `name` FROM `user` LIMIT 0").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE `tmp_user_[0-9]+` ADD PRIMARY KEY \\(`id`\\)").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `tmp_user_[0-9]+` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\),\\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 2, "Name2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE `user` JOIN `tmp_user_[0-9]+` USING \\(`id`\\) SET `user`.`name` = `tmp_user_[0-9]+`.`name`").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DROP TEMPORARY TABLE IF EXISTS `tmp_user_[0-9]+`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = db.UpdateTempTable("user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMPORARY TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `tmp_user_[0-9]+`").WillReturnError(errors.New("failed"))
+		mock.ExpectExec("DROP TEMPORARY TABLE IF EXISTS `tmp_user_[0-9]+`").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = db.UpdateTempTable("user", data(), []string{"id"}, 2)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wide", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		// 100 rows of 700 fields are more than maximum placeholder, even though field size is 1
+		wide := []map[string]any{}
+		for i := 0; i < 100; i++ {
+			item := map[string]any{"id": i}
+			for field := 1; field < 700; field++ {
+				item[fmt.Sprintf("field_%d", field)] = field
+			}
+			wide = append(wide, item)
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMPORARY TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ALTER TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `tmp_user_[0-9]+`").WillReturnResult(sqlmock.NewResult(0, 93))
+		mock.ExpectExec("INSERT INTO `tmp_user_[0-9]+`").WillReturnResult(sqlmock.NewResult(0, 7))
+		mock.ExpectExec("UPDATE `user` JOIN").WillReturnResult(sqlmock.NewResult(0, 100))
+		mock.ExpectExec("DROP TEMPORARY TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err = db.UpdateTempTable("user", wide, []string{"id"}, 1)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		err = db.UpdateTempTable("user", data(), []string{"non_exists"}, 2)
+		assert.NotNil(t, err)
+
+		err = db.UpdateTempTable("user", []map[string]any{{"id": 1}}, []string{"id"}, 1)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WithTx is used to get SQL view that executes every query using caller-owned transaction
+//
+// single transaction can't be used concurrently, so pages are executed one by one,
+// and failed query is not retried because the transaction may already be rolled back
+//
+// commit and rollback are responsibility of the caller
+func (s *sql) WithTx(tx *sqlx.Tx) SQL {
+	return s.withTx(tx)
+}
+
+func (s *sql) withTx(tx *sqlx.Tx) *sql {
+	view := *s
+	view.ext = tx
+	view.tx = tx
+	view.owned = false
+	view.workerSize = 1
+	view.retry = retry{attempts: 1}
+	return &view
+}
+
+// Transaction is used to execute fn inside new transaction
+//
+// commit when fn succeed, otherwise rollback
+//
+// when SQL already uses transaction, fn is executed using the same transaction
+func (s *sql) Transaction(ctx context.Context, fn func(tx SQL) error) error {
+	return s.transaction(ctx, func(tx *sql) error {
+		return fn(tx)
+	})
+}
+
+func (s *sql) transaction(ctx context.Context, fn func(tx *sql) error) (err error) {
+	if s.tx != nil {
+		return fn(s)
+	}
+	if s.db == nil {
+		return errors.New("transaction needs database")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = fmt.Errorf("%w, failed rollback: %v", err, rollbackErr)
+			}
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("failed commit: %w", err)
+		}
+	}()
+
+	return fn(s.withTx(tx))
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	data := func() []map[string]any {
+		return []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2"}}
+	}
+
+	t.Run("with tx", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(8), WithBatchSize(1))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO audit").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(1, "Name1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(2, "Name2", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		tx, err := db.DB().Beginx()
+		require.Nil(t, err)
+		_, err = tx.Exec("INSERT INTO audit (action) VALUES ('update')")
+		require.Nil(t, err)
+
+		err = db.WithTx(tx).UpdateBulk("user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Commit())
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `user`").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM `user` WHERE `id` = \\?").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = db.Transaction(ctx, func(tx SQL) error {
+			if err := tx.CreateBulk("user", data(), 2); err != nil {
+				return err
+			}
+			// nested transaction uses the same transaction
+			return tx.Transaction(ctx, func(tx SQL) error {
+				return tx.Delete("user", map[string]any{"id": 3})
+			})
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `user`").WillReturnError(errors.New("failed"))
+		mock.ExpectRollback()
+
+		err = db.Transaction(ctx, func(tx SQL) error {
+			return tx.CreateBulk("user", data(), 2)
+		})
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
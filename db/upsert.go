@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"strings"
+	"time"
+)
+
+func (s *sql) UpdateUpsert(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateUpsertContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+// UpdateUpsertContext is used to bulk update using INSERT ... ON DUPLICATE KEY UPDATE
+//
+// keyEdits need to be primary or unique key of the table
+//
+// by default each page checks all of its keys exist before executing, see WithUpsertGuard,
+// the guard refuses table that has other primary or unique key than keyEdits,
+// because ON DUPLICATE KEY UPDATE may match and overwrite other row using that key
+func (s *sql) UpdateUpsertContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+
+	if err := s.rejectDialect("upsert"); err != nil {
+		return err
+	}
+	if err := s.rejectVersion("upsert", table); err != nil {
+		return err
+	}
+	if len(s.guard) > 0 {
+		return errors.New("upsert doesn't support Where guard, it may insert rows that don't match the guard")
+	}
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	totalField := utils.BulkUpdateValuesEstimateTotalField(len(data), fieldSize)
+	paged := utils.PagedData(data, s.updatePageSize(len(data), totalField))
+
+	op := OperationEvent{Operation: "UpdateUpsert", Strategy: "upsert", Table: table, Rows: len(data), Pages: len(paged)}
+
+	if !s.upsertGuard {
+		return s.bulkUpdate(ctx, op, paged, keyEdits, func(data []map[string]any) (string, map[string]any, error) {
+			return utils.BulkUpsertQuery(s.dialect, table, data, keyEdits)
+		})
+	}
+
+	if err := s.rejectOtherKeys(ctx, table, keyEdits); err != nil {
+		return err
+	}
+
+	update := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		query, binds, err := utils.BulkUpsertQuery(s.dialect, table, data, keyEdits)
+		if err != nil {
+			return fmt.Errorf("failed to build query %d: %w", pageNumber, err)
+		}
+		guardQuery, guardBinds, err := utils.CountKeysQuery(s.dialect, table, data, keyEdits, true)
+		if err != nil {
+			return fmt.Errorf("failed to build guard query %d: %w", pageNumber, err)
+		}
+
+		return s.transaction(ctx, func(tx *sql) error {
+			counts := []int{}
+			guardEvent := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: table}
+			if err := tx.namedSelect(ctx, guardEvent, &counts, guardQuery, guardBinds); err != nil {
+				return fmt.Errorf("error when guard page %d: %w", pageNumber, err)
+			}
+			found := 0
+			if len(counts) > 0 {
+				found = counts[0]
+			}
+			if expected := countDistinctKeys(data, keyEdits); found != expected {
+				return fmt.Errorf("page %d: only %d of %d keys exist, upsert canceled to avoid insert", pageNumber, found, expected)
+			}
+
+			event := QueryEvent{
+				Operation:    op.Operation,
+				Strategy:     op.Strategy,
+				Table:        table,
+				Page:         pageNumber,
+				Rows:         len(data),
+				Placeholders: len(binds),
+				WaitTime:     waitTime,
+			}
+			return tx.audited(ctx, op, data, keyEdits, func() error {
+				if _, err := tx.namedExec(ctx, event, query, binds); err != nil {
+					return fmt.Errorf("error when update page %d: %w", pageNumber, err)
+				}
+				return nil
+			})
+		})
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		return s.runPages(s.auditJob(ctx), paged, update)
+	})
+}
+
+// rejectOtherKeys is used to make sure keyEdits is the only primary or unique key of the table,
+// so guarded keys are the only rows ON DUPLICATE KEY UPDATE can match
+func (s *sql) rejectOtherKeys(ctx context.Context, table string, keyEdits []string) error {
+	keys, err := s.Keys(ctx, table)
+	if err != nil {
+		return fmt.Errorf("failed check keys of upsert guard: %w", err)
+	}
+	edits := make(map[string]bool, len(keyEdits))
+	for _, key := range keyEdits {
+		edits[s.dialect.NormalizeColumn(key)] = true
+	}
+	for _, key := range keys {
+		same := len(key.Columns) == len(edits)
+		for _, column := range key.Columns {
+			same = same && edits[s.dialect.NormalizeColumn(column)]
+		}
+		if !same {
+			return fmt.Errorf("upsert guard can't protect key '%s' of %s, it may overwrite other row, use other strategy or WithUpsertGuard(false)", key.Name, table)
+		}
+	}
+	return nil
+}
+
+// countDistinctKeys is used to count unique combination of keyEdits value in data
+func countDistinctKeys(data []map[string]any, keyEdits []string) int {
+	keys := map[string]struct{}{}
+	for _, item := range data {
+		values := []string{}
+		for _, key := range keyEdits {
+			values = append(values, fmt.Sprintf("%v", item[key]))
+		}
+		keys[strings.Join(values, "\x00")] = struct{}{}
+	}
+	return len(keys)
+}
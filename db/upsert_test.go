@@ -0,0 +1,100 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUpsert(t *testing.T) {
+	data := func() []map[string]any {
+		return []map[string]any{{"id": 1, "name": "Name1"}, {"id": 2, "name": "Name2"}}
+	}
+	upsertQuery := "INSERT INTO `user` \\(`id`, `name`\\) VALUES \\(\\?, \\?\\), \\(\\?, \\?\\) ON DUPLICATE KEY UPDATE `name` = VALUES\\(`name`\\)"
+	keysQuery := "SELECT tc.CONSTRAINT_NAME AS key_name, tc.CONSTRAINT_TYPE AS key_type, kcu.COLUMN_NAME AS column_name FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc"
+	keys := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"key_name", "key_type", "column_name"}).AddRow("PRIMARY", "PRIMARY KEY", "id")
+	}
+	guardQuery := "SELECT COUNT\\(\\*\\) FROM `user` WHERE `id` IN \\(\\?, \\?\\) FOR UPDATE"
+
+	t.Run("guarded", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectQuery(keysQuery).WithArgs("user").WillReturnRows(keys())
+		mock.ExpectBegin()
+		mock.ExpectQuery(guardQuery).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(2))
+		mock.ExpectExec(upsertQuery).WithArgs(1, "Name1", 2, "Name2").WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectCommit()
+
+		err = db.UpdateUpsert("user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("guarded missing rows", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		mock.ExpectQuery(keysQuery).WithArgs("user").WillReturnRows(keys())
+		mock.ExpectBegin()
+		mock.ExpectQuery(guardQuery).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err = db.UpdateUpsert("user", data(), []string{"id"}, 2)
+		assert.NotNil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("guarded other unique key", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB)
+		require.Nil(t, err)
+
+		// row of other email would be overwritten instead of duplicate key error
+		mock.ExpectQuery(keysQuery).WithArgs("user").
+			WillReturnRows(keys().AddRow("email", "UNIQUE", "email"))
+
+		err = db.UpdateUpsert("user", data(), []string{"id"}, 2)
+		assert.EqualError(t, err, "upsert guard can't protect key 'email' of user, it may overwrite other row, use other strategy or WithUpsertGuard(false)")
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not guarded", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithUpsertGuard(false))
+		require.Nil(t, err)
+
+		mock.ExpectExec(upsertQuery).WithArgs(1, "Name1", 2, "Name2").WillReturnResult(sqlmock.NewResult(0, 4))
+
+		err = db.UpdateUpsert("user", data(), []string{"id"}, 2)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCountDistinctKeys(t *testing.T) {
+	data := []map[string]any{
+		{"id": 1, "code": "a"},
+		{"id": 1, "code": "b"},
+		{"id": 1, "code": "a"},
+	}
+	assert.Equal(t, 2, countDistinctKeys(data, []string{"id", "code"}))
+	assert.Equal(t, 1, countDistinctKeys(data, []string{"id"}))
+}
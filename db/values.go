@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"go_update_bulk/utils"
+)
+
+func (s *sql) UpdateValues(table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	return s.UpdateValuesContext(context.Background(), table, data, keyEdits, fieldSize)
+}
+
+// UpdateValuesContext is used to bulk update by joining the table with VALUES derived table
+//
+// need MySQL 8.0.19 or later, placeholders of each page only fields * rows
+func (s *sql) UpdateValuesContext(ctx context.Context, table string, data []map[string]any, keyEdits []string, fieldSize int) error {
+	if table == "" {
+		return errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return errors.New("data is empty")
+	}
+	keyEdits, err := s.resolveKeyEdits(ctx, table, keyEdits)
+	if err != nil {
+		return err
+	}
+	if fieldSize <= 0 {
+		return errors.New("field size minimum 1")
+	}
+
+	if err := s.rejectDialect("values"); err != nil {
+		return err
+	}
+	if err := s.rejectVersion("values", table); err != nil {
+		return err
+	}
+	data, err = s.checkColumns(ctx, table, data, keyEdits)
+	if err != nil {
+		return err
+	}
+	data, err = s.checkValues(ctx, table, data)
+	if err != nil {
+		return err
+	}
+
+	totalField := utils.BulkUpdateValuesEstimateTotalField(len(data), fieldSize)
+	paged := utils.PagedData(data, s.updatePageSize(len(data), totalField))
+
+	op := OperationEvent{Operation: "UpdateValues", Strategy: "values", Table: table, Rows: len(data), Pages: len(paged)}
+	return s.bulkUpdate(ctx, op, paged, keyEdits, func(data []map[string]any) (string, map[string]any, error) {
+		query, binds, err := utils.BulkUpdateValuesQuery(s.dialect, table, data, keyEdits)
+		if err != nil {
+			return "", nil, err
+		}
+		return s.applyGuard(table, query, binds, "WHERE")
+	})
+}
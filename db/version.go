@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/utils"
+	"sync"
+	"time"
+)
+
+// version is optimistic concurrency column of the table, see WithVersion
+type version struct {
+	column string
+	// next is expression that sets the new version, empty means column + 1
+	next utils.Expr
+}
+
+// ConflictError is returned when some data are not updated because their version was changed by others,
+// other data are still updated, so only the lost data need to be retried
+//
+// when other data are not updated for other reason as well, Unwrap returns NotUpdatedError
+type ConflictError struct {
+	Table string
+	// Keys are key edits and expected version of every lost data
+	Keys []map[string]any
+	// notUpdated is data that are not updated for other reason
+	notUpdated error
+}
+
+func (e *ConflictError) Error() string {
+	if e.notUpdated != nil {
+		return fmt.Sprintf("%d data of %s lost version conflict, %v", len(e.Keys), e.Table, e.notUpdated)
+	}
+	return fmt.Sprintf("%d data of %s lost version conflict", len(e.Keys), e.Table)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.notUpdated
+}
+
+// NotUpdatedError is returned when some data of table with version column are not updated,
+// but their version is still the same, so retry would not help
+type NotUpdatedError struct {
+	Table string
+	// Missing are key edits and expected version of data which row doesn't exist
+	Missing []map[string]any
+	// Unmatched are key edits and expected version of data which row doesn't match Where guard
+	Unmatched []map[string]any
+}
+
+func (e *NotUpdatedError) Error() string {
+	return fmt.Sprintf("%d data of %s not found, %d data don't match guard", len(e.Missing), e.Table, len(e.Unmatched))
+}
+
+// conflicts is used to collect lost and not updated data of every page concurrently
+type conflicts struct {
+	mu        sync.Mutex
+	keys      []map[string]any
+	missing   []map[string]any
+	unmatched []map[string]any
+}
+
+func (c *conflicts) add(keys ...map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = append(c.keys, keys...)
+}
+
+func (c *conflicts) addMissing(keys ...map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missing = append(c.missing, keys...)
+}
+
+func (c *conflicts) addUnmatched(keys ...map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unmatched = append(c.unmatched, keys...)
+}
+
+// collect is used to add data of ConflictError and NotUpdatedError, other errors are returned as it is
+func (c *conflicts) collect(err error) error {
+	conflictErr := &ConflictError{}
+	notUpdatedErr := &NotUpdatedError{}
+	switch {
+	case errors.As(err, &conflictErr):
+		c.add(conflictErr.Keys...)
+		if errors.As(conflictErr.notUpdated, &notUpdatedErr) {
+			c.addMissing(notUpdatedErr.Missing...)
+			c.addUnmatched(notUpdatedErr.Unmatched...)
+		}
+		return nil
+	case errors.As(err, &notUpdatedErr):
+		c.addMissing(notUpdatedErr.Missing...)
+		c.addUnmatched(notUpdatedErr.Unmatched...)
+		return nil
+	}
+	return err
+}
+
+func (c *conflicts) err(table string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var notUpdated error
+	if len(c.missing) > 0 || len(c.unmatched) > 0 {
+		notUpdated = &NotUpdatedError{Table: table, Missing: c.missing, Unmatched: c.unmatched}
+	}
+	if len(c.keys) == 0 {
+		return notUpdated
+	}
+	return &ConflictError{Table: table, Keys: c.keys, notUpdated: notUpdated}
+}
+
+// versionOf is used to get version column of the table
+func (s *sql) versionOf(table string) (version, bool) {
+	v, ok := s.versions[table]
+	return v, ok
+}
+
+// rejectVersion is used by strategies that can't check version of every data
+func (s *sql) rejectVersion(strategy, table string) error {
+	if _, ok := s.versionOf(table); ok {
+		return fmt.Errorf("%s doesn't support version column of %s, use case, parallel or sequential", strategy, table)
+	}
+	return nil
+}
+
+// nextVersion is expression that sets the new version of the column
+func (s *sql) nextVersion(v version) (utils.Expr, error) {
+	if v.next.SQL != "" {
+		return v.next, nil
+	}
+	quoted, err := s.dialect.QuoteIdentifier(v.column)
+	if err != nil {
+		return utils.Expr{}, err
+	}
+	return utils.NewExpr(quoted+" + 1", nil), nil
+}
+
+// versionedUpdate is used by single update to move expected version from payload to condition,
+// and to set the new version, payload and condition are copied
+func (s *sql) versionedUpdate(v version, data, condition map[string]any) (map[string]any, map[string]any, error) {
+	data = utils.ResolveOptional(data)
+	expected, ok := data[v.column]
+	if !ok {
+		return nil, nil, fmt.Errorf("version '%s' not found in the data", v.column)
+	}
+	next, err := s.nextVersion(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload := make(map[string]any, len(data))
+	for key, value := range data {
+		payload[key] = value
+	}
+	payload[v.column] = next
+
+	where := make(map[string]any, len(condition)+1)
+	for key, value := range condition {
+		where[key] = value
+	}
+	where[v.column] = expected
+	return payload, where, nil
+}
+
+// versionMiss is used when single update of versioned data affects no row, the row is read again to
+// tell version conflict from missing row or row that doesn't match Where guard
+//
+// condition is the condition of the update, including the expected version
+func (s *sql) versionMiss(ctx context.Context, event QueryEvent, table string, v version, condition map[string]any) error {
+	keys := make(map[string]any, len(condition))
+	for key, value := range condition {
+		if key != v.column {
+			keys[key] = value
+		}
+	}
+	query, binds, err := utils.SelectQuery(s.dialect, table, []string{v.column}, &keys, nil)
+	if err != nil {
+		return fmt.Errorf("failed build version query: %w", err)
+	}
+
+	// supporting query, not the page query
+	readEvent := QueryEvent{Operation: event.Operation, Strategy: event.Strategy, Table: table}
+	rows, err := s.namedSelectMaps(ctx, readEvent, query, binds)
+	if err != nil {
+		return fmt.Errorf("failed read version: %w", err)
+	}
+	switch {
+	case len(rows) == 0:
+		return &NotUpdatedError{Table: table, Missing: []map[string]any{condition}}
+	case utils.SameValue(rows[0][v.column], condition[v.column]):
+		return &NotUpdatedError{Table: table, Unmatched: []map[string]any{condition}}
+	}
+	return &ConflictError{Table: table, Keys: []map[string]any{condition}}
+}
+
+// updateVersioned is used by UpdateBulk when the table has version column, each page locks rows of its keys
+// using SELECT ... FOR UPDATE, then only rows that still have the expected version are updated and their version is set
+//
+// data which version is changed are returned together as ConflictError after all pages are executed,
+// data which row doesn't exist or doesn't match Where guard are returned as NotUpdatedError
+func (s *sql) updateVersioned(ctx context.Context, op OperationEvent, paged [][]map[string]any, keyEdits []string, v version) error {
+	next, err := s.nextVersion(v)
+	if err != nil {
+		return err
+	}
+	lockKeys := append(append([]string{}, keyEdits...), v.column)
+	lost := &conflicts{}
+
+	update := func(ctx context.Context, pageNumber int, data []map[string]any, waitTime time.Duration) error {
+		data = utils.ResolveOptionals(data)
+		for index, item := range data {
+			if _, ok := item[v.column]; !ok {
+				return fmt.Errorf("version '%s' not found in the data number %d of page %d", v.column, index+1, pageNumber)
+			}
+		}
+		selectQuery, selectBinds, err := utils.SelectKeysQuery(s.dialect, op.Table, append([]string{}, lockKeys...), data, append([]string{}, keyEdits...), true)
+		if err != nil {
+			return fmt.Errorf("failed to build lock query %d: %w", pageNumber, err)
+		}
+		// rows are already locked, so rows that match the guard are selected without FOR UPDATE
+		guardQuery, guardBinds := "", map[string]any{}
+		if len(s.guard) > 0 {
+			guardQuery, guardBinds, err = utils.SelectKeysQuery(s.dialect, op.Table, append([]string{}, keyEdits...), data, append([]string{}, keyEdits...), false)
+			if err != nil {
+				return fmt.Errorf("failed to build guard query %d: %w", pageNumber, err)
+			}
+			guardQuery, guardBinds, err = s.applyGuard(op.Table, guardQuery, guardBinds, "AND")
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.transaction(ctx, func(tx *sql) error {
+			// supporting query, only the update is the page query
+			lockEvent := QueryEvent{Operation: op.Operation, Strategy: op.Strategy, Table: op.Table}
+			current, err := tx.namedSelectMaps(ctx, lockEvent, selectQuery, selectBinds)
+			if err != nil {
+				return fmt.Errorf("error when lock page %d: %w", pageNumber, err)
+			}
+			rows, err := rowsByKey(current, keyEdits)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pageNumber, err)
+			}
+			var guarded map[string]map[string]any
+			if guardQuery != "" {
+				matched, err := tx.namedSelectMaps(ctx, lockEvent, guardQuery, guardBinds)
+				if err != nil {
+					return fmt.Errorf("error when guard page %d: %w", pageNumber, err)
+				}
+				if guarded, err = rowsByKey(matched, keyEdits); err != nil {
+					return fmt.Errorf("page %d: %w", pageNumber, err)
+				}
+			}
+
+			matched := []map[string]any{}
+			for _, item := range data {
+				key, err := utils.RowKey(item, keyEdits)
+				if err != nil {
+					return fmt.Errorf("page %d: %w", pageNumber, err)
+				}
+				row, ok := rows[key]
+				switch {
+				case !ok:
+					lost.addMissing(utils.PickFields(item, lockKeys))
+					continue
+				case !utils.SameValue(row[v.column], item[v.column]):
+					lost.add(utils.PickFields(item, lockKeys))
+					continue
+				case guarded != nil && guarded[key] == nil:
+					lost.addUnmatched(utils.PickFields(item, lockKeys))
+					continue
+				}
+				updated := make(map[string]any, len(item))
+				for field, value := range item {
+					updated[field] = value
+				}
+				updated[v.column] = next
+				matched = append(matched, updated)
+			}
+			// lost rows are processed as well, so the page event counts every data
+			event := QueryEvent{
+				Operation: op.Operation,
+				Strategy:  op.Strategy,
+				Table:     op.Table,
+				Page:      pageNumber,
+				Rows:      len(data),
+				WaitTime:  waitTime,
+			}
+			if len(matched) == 0 {
+				tx.hooks.skip(ctx, &event)
+				return nil
+			}
+
+			query, binds, err := utils.BulkUpdateQuery(tx.dialect, op.Table, matched, keyEdits)
+			if err != nil {
+				return fmt.Errorf("failed to build query %d: %w", pageNumber, err)
+			}
+			query, binds, err = tx.applyGuard(op.Table, query, binds, "AND")
+			if err != nil {
+				return err
+			}
+			event.Placeholders = len(binds)
+			return tx.audited(ctx, op, matched, keyEdits, func() error {
+				if _, err := tx.namedExec(ctx, event, query, binds); err != nil {
+					return fmt.Errorf("error when update page %d: %w", pageNumber, err)
+				}
+				return nil
+			})
+		})
+	}
+
+	return s.hooks.operation(ctx, &op, func(ctx context.Context) error {
+		if err := s.runPages(s.auditJob(ctx), paged, update); err != nil {
+			return err
+		}
+		return lost.err(op.Table)
+	})
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"errors"
+	"go_update_bulk/utils"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	data := func() []map[string]any {
+		return []map[string]any{
+			{"id": 1, "name": "Name1", "version": 3},
+			{"id": 2, "name": "Name2", "version": 1},
+		}
+	}
+
+	t.Run("bulk", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		hook := &recordHook{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithVersion("user", "version"), WithHooks(hook))
+		require.Nil(t, err)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT `id`, `version` FROM `user` WHERE `id` IN \\(\\?, \\?\\) FOR UPDATE").
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(int64(1), int64(3)).AddRow(int64(2), int64(2)))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? ELSE `name` END \\), "+
+			"`version` = \\( CASE WHEN `id` = \\? THEN `version` \\+ 1 ELSE `version` END \\) WHERE `id` IN \\(\\?\\)").
+			WithArgs(1, "Name1", 1, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = db.UpdateBulk("user", data(), []string{"id"}, 3)
+		conflictErr := &ConflictError{}
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, "user", conflictErr.Table)
+		assert.Equal(t, []map[string]any{{"id": 2, "version": 1}}, conflictErr.Keys)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// lock is supporting query, so only the update is counted as page
+		require.Len(t, hook.after, 2)
+		assert.Equal(t, 0, hook.after[0].Page)
+		assert.Equal(t, 1, hook.after[1].Page)
+	})
+
+	t.Run("datetime", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		next := utils.NewExpr("CURRENT_TIMESTAMP(6)", nil)
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithVersionExpr("user", "version", next))
+		require.Nil(t, err)
+
+		// DATETIME(6) text keeps trailing zeros of fractional second
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT `id`, `version` FROM `user` WHERE `id` IN \\(\\?, \\?\\) FOR UPDATE").
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).
+				AddRow([]byte("1"), []byte("2024-01-01 10:00:00.000000")).
+				AddRow([]byte("2"), []byte("2024-01-01 10:00:00.500000")))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\( CASE WHEN `id` = \\? THEN \\? WHEN `id` = \\? THEN \\? ELSE `name` END \\), "+
+			"`version` = \\( CASE WHEN `id` = \\? THEN CURRENT_TIMESTAMP\\(6\\) WHEN `id` = \\? THEN CURRENT_TIMESTAMP\\(6\\) ELSE `version` END \\) WHERE `id` IN \\(\\?, \\?\\)").
+			WithArgs(1, "Name1", 2, "Name2", 1, 2, 1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		data := []map[string]any{
+			{"id": 1, "name": "Name1", "version": time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)},
+			{"id": 2, "name": "Name2", "version": time.Date(2024, 1, 1, 10, 0, 0, 500000000, time.UTC)},
+		}
+		err = db.UpdateBulk("user", data, []string{"id"}, 3)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not updated", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		progress := []Progress{}
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithVersion("user", "version"), WithHooks(NewProgressHook(func(p Progress) {
+			progress = append(progress, p)
+		})))
+		require.Nil(t, err)
+
+		// data 1 doesn't match the guard, data 2 doesn't exist, data 3 lost version conflict
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT `id`, `version` FROM `user` WHERE `id` IN \\(\\?, \\?, \\?\\) FOR UPDATE").
+			WithArgs(1, 2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(int64(1), int64(3)).AddRow(int64(3), int64(5)))
+		mock.ExpectQuery("SELECT `id` FROM `user` WHERE `id` IN \\(\\?, \\?, \\?\\) AND `user`.`status` = \\?$").
+			WithArgs(1, 2, 3, "pending").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		data := append(data(), map[string]any{"id": 3, "name": "Name3", "version": 4})
+		err = db.Where(map[string]any{"status": "pending"}).UpdateBulk("user", data, []string{"id"}, 3)
+		conflictErr := &ConflictError{}
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, []map[string]any{{"id": 3, "version": 4}}, conflictErr.Keys)
+		notUpdatedErr := &NotUpdatedError{}
+		require.ErrorAs(t, err, &notUpdatedErr)
+		assert.Equal(t, []map[string]any{{"id": 2, "version": 1}}, notUpdatedErr.Missing)
+		assert.Equal(t, []map[string]any{{"id": 1, "version": 3}}, notUpdatedErr.Unmatched)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// page without update still counts its rows
+		require.NotEmpty(t, progress)
+		last := progress[len(progress)-1]
+		assert.Equal(t, 1, last.PagesDone)
+		assert.Equal(t, 3, last.RowsDone)
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		next := utils.NewExpr("CURRENT_TIMESTAMP(6)", nil)
+		db, err := NewSQLFromStdDB(mockDB, WithWorkers(1), WithVersionExpr("user", "version", next))
+		require.Nil(t, err)
+
+		mock.ExpectExec("UPDATE `user` SET `name` = \\?, `version` = CURRENT_TIMESTAMP\\(6\\) WHERE `id` = \\? AND `version` = \\?").
+			WithArgs("Name1", 1, 3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT `version` FROM `user` WHERE `id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow([]byte("2023-01-02 03:04:05.000001")))
+		mock.ExpectExec("UPDATE `user` SET `name` = \\?, `version` = CURRENT_TIMESTAMP\\(6\\) WHERE `id` = \\? AND `version` = \\?").
+			WithArgs("Name2", 2, 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT `version` FROM `user` WHERE `id` = \\?").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+		err = db.UpdateSequential("user", data(), []string{"id"}, 3)
+		conflictErr := &ConflictError{}
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, []map[string]any{{"id": 1, "version": 3}}, conflictErr.Keys)
+		notUpdatedErr := &NotUpdatedError{}
+		require.ErrorAs(t, err, &notUpdatedErr)
+		assert.Equal(t, []map[string]any{{"id": 2, "version": 1}}, notUpdatedErr.Missing)
+		assert.Nil(t, mock.ExpectationsWereMet())
+
+		// row that still has the version doesn't match the guard
+		mock.ExpectExec("UPDATE `user` SET `name` = \\?, `version` = CURRENT_TIMESTAMP\\(6\\) WHERE `id` = \\? AND `version` = \\? AND `user`.`status` = \\?").
+			WithArgs("Name1", 1, 3, "pending").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT `version` FROM `user` WHERE `id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow([]byte("3")))
+
+		err = db.Where(map[string]any{"status": "pending"}).Update("user", map[string]any{"name": "Name1", "version": 3}, map[string]any{"id": 1})
+		assert.False(t, errors.As(err, &conflictErr))
+		require.ErrorAs(t, err, &notUpdatedErr)
+		assert.Equal(t, []map[string]any{{"id": 1, "version": 3}}, notUpdatedErr.Unmatched)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockDB, _, err := sqlmock.New()
+		require.Nil(t, err)
+		defer mockDB.Close()
+
+		db, err := NewSQLFromStdDB(mockDB, WithVersion("user", "version"))
+		require.Nil(t, err)
+
+		err = db.UpdateValues("user", data(), []string{"id"}, 3)
+		assert.NotNil(t, err)
+
+		err = db.Update("user", map[string]any{"name": "Name1"}, map[string]any{"id": 1})
+		assert.EqualError(t, err, "version 'version' not found in the data")
+	})
+}
@@ -1,21 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"go_update_bulk/db"
 	"go_update_bulk/generator"
+	"io"
 	"log"
 	"os"
-	"reflect"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 type BulkUpdateOption struct {
 	generator  generator.Generator
-	method     string
+	strategy   string
 	clearAtEnd bool
-	keyEdits   []string
 }
 
 func ExecBulkUpdate(sql db.SQL, opt BulkUpdateOption) error {
@@ -34,22 +36,17 @@ func ExecBulkUpdate(sql db.SQL, opt BulkUpdateOption) error {
 	}
 
 	// Update
-	// func fn(table string, data []map[string]any, keyEdits []string, fieldSize int) error
-	startTime := time.Now()
-	method := reflect.ValueOf(sql).MethodByName(opt.method)
-	params := []reflect.Value{}
-
-	for _, param := range []any{table, opt.generator.GetUpdate(), opt.keyEdits, fieldSize} {
-		params = append(params, reflect.ValueOf(param))
+	strategy, err := db.LookupStrategy(opt.strategy)
+	if err != nil {
+		return err
 	}
-	result := method.Call(params)
-	if len(result) > 0 {
-		if err := result[0].Interface(); err != nil {
-			return err.(error)
-		}
+	// key edits default to the primary key of the table
+	startTime := time.Now()
+	if err := strategy.Update(context.Background(), sql, table, opt.generator.GetUpdate(), nil, fieldSize); err != nil {
+		return err
 	}
 	elapsed := time.Since(startTime)
-	log.Printf("%s with %d data took %fs\n", opt.method, opt.generator.TotalData(), elapsed.Seconds())
+	log.Printf("%s with %d data took %fs\n", strategy.Name(), opt.generator.TotalData(), elapsed.Seconds())
 
 	// Clear
 	if opt.clearAtEnd {
@@ -60,6 +57,80 @@ func ExecBulkUpdate(sql db.SQL, opt BulkUpdateOption) error {
 	return nil
 }
 
+// ProgressBar is used to render progress of all running bulk operations in single terminal line
+//
+// it also implements io.Writer, so log output will not break the rendered line
+type ProgressBar struct {
+	mu       sync.Mutex
+	out      io.Writer
+	width    int
+	order    []string
+	progress map[string]db.Progress
+}
+
+func NewProgressBar(out io.Writer, width int) *ProgressBar {
+	return &ProgressBar{out: out, width: width, progress: map[string]db.Progress{}}
+}
+
+// Update is used to render progress, operations of different strategy or table have their own bar
+func (p *ProgressBar) Update(progress db.Progress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := fmt.Sprintf("%s %s %s", progress.Operation, progress.Strategy, progress.Table)
+	if _, ok := p.progress[key]; !ok {
+		p.order = append(p.order, key)
+	}
+	p.progress[key] = progress
+	p.render()
+}
+
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.out, "\r\033[K")
+	n, err := p.out.Write(b)
+	p.render()
+	return n, err
+}
+
+// Finish is used to move cursor to the next line and stop rendering after all operations done
+func (p *ProgressBar) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.order) > 0 {
+		fmt.Fprintln(p.out)
+	}
+	p.order = nil
+	p.progress = map[string]db.Progress{}
+}
+
+func (p *ProgressBar) render() {
+	if len(p.order) == 0 {
+		return
+	}
+	parts := []string{}
+	for _, key := range p.order {
+		progress := p.progress[key]
+		// percent may be out of [0, 100], e.g. when rows are counted more than once
+		filled := int(progress.Percent() / 100 * float64(p.width))
+		if filled < 0 {
+			filled = 0
+		} else if filled > p.width {
+			filled = p.width
+		}
+		bar := strings.Repeat("=", filled) + strings.Repeat(" ", p.width-filled)
+		parts = append(parts, fmt.Sprintf(
+			"%s [%s] %3.0f%% %.0f rows/s ETA %s",
+			key,
+			bar,
+			progress.Percent(),
+			progress.RowsPerSecond,
+			progress.ETA.Round(time.Second),
+		))
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", strings.Join(parts, " | "))
+}
+
 func main() {
 	args := os.Args
 
@@ -88,31 +159,45 @@ func main() {
 
 	clearAtEnd := false
 	dataSourceName := "root:root@(localhost:3307)/test_db"
-	keyEdits := []string{"id"}
+
+	bar := NewProgressBar(os.Stderr, 20)
+	log.SetOutput(bar)
 
 	log.Println("Start")
 	defer log.Println("Finish")
 
 	// Connect database
-	sql, err := db.NewSQL(dataSourceName, worker, updateBatchSize)
+	sql, err := db.NewSQL(
+		dataSourceName,
+		db.WithWorkers(worker),
+		db.WithBatchSize(updateBatchSize),
+		db.WithLogger(log.Default()),
+		db.WithRetry(3, 100*time.Millisecond),
+		db.WithHooks(db.NewProgressHook(bar.Update)),
+		db.WithKeyCheck(true),
+	)
 	if err != nil {
 		panic(err)
 	}
 	defer sql.Close()
 
 	var wg sync.WaitGroup
-	methods := []string{
-		"UpdateSequential",
-		"UpdateParallel",
-		"UpdateBulk",
+	strategies := []string{
+		"sequential",
+		"parallel",
+		"case",
+		"temp_table",
+		"values",
+		"upsert",
+		"load_data",
+		"auto",
 	}
 
-	for _, method := range methods {
+	for _, strategy := range strategies {
 		gen := generator.NewGenerator(start, size, generator.NewUserDump(), "db", true)
 		opt := BulkUpdateOption{
 			generator:  gen,
-			method:     method,
-			keyEdits:   keyEdits,
+			strategy:   strategy,
 			clearAtEnd: clearAtEnd,
 		}
 		start += size
@@ -126,4 +211,5 @@ func main() {
 		}(opt)
 	}
 	wg.Wait()
+	bar.Finish()
 }
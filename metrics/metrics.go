@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"go_update_bulk/db"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is used to instrument db.SQL and expose the result as prometheus metrics
+//
+// register it using db.WithHooks and as prometheus.Collector on the registry
+//
+// rows/sec can be queried using rate() of the rows total metric
+type Collector struct {
+	rows          *prometheus.CounterVec
+	queries       *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	pagesInFlight *prometheus.GaugeVec
+	pageDuration  *prometheus.HistogramVec
+	semaphoreWait *prometheus.HistogramVec
+}
+
+var _ db.Hook = &Collector{}
+var _ prometheus.Collector = &Collector{}
+
+func NewCollector(namespace string) *Collector {
+	labels := []string{"operation", "table"}
+	return &Collector{
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rows_total",
+			Help:      "Total rows successfully processed.",
+		}, labels),
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "queries_total",
+			Help:      "Total executed queries.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Total failed queries by MySQL error code.",
+		}, append(labels, "code")),
+		pagesInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pages_in_flight",
+			Help:      "Pages currently executed.",
+		}, labels),
+		pageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "page_duration_seconds",
+			Help:      "Latency of each page query.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+		}, labels),
+		semaphoreWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "semaphore_wait_seconds",
+			Help:      "Time each page waited for a free worker.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, labels),
+	}
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.rows, c.queries, c.errors, c.pagesInFlight, c.pageDuration, c.semaphoreWait}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) BeforeQuery(ctx context.Context, event *db.QueryEvent) context.Context {
+	if event.Page > 0 {
+		c.pagesInFlight.WithLabelValues(event.Operation, event.Table).Inc()
+		c.semaphoreWait.WithLabelValues(event.Operation, event.Table).Observe(event.WaitTime.Seconds())
+	}
+	return ctx
+}
+
+func (c *Collector) AfterQuery(ctx context.Context, event *db.QueryEvent) {
+	c.queries.WithLabelValues(event.Operation, event.Table).Inc()
+	if event.Page > 0 {
+		c.pagesInFlight.WithLabelValues(event.Operation, event.Table).Dec()
+		c.pageDuration.WithLabelValues(event.Operation, event.Table).Observe(event.Duration.Seconds())
+	}
+	if event.Err != nil {
+		c.errors.WithLabelValues(event.Operation, event.Table, ErrorCode(event.Err)).Inc()
+		return
+	}
+	// supporting queries, e.g. version lock or diff select, read the same rows the page updates
+	if event.Page > 0 {
+		c.rows.WithLabelValues(event.Operation, event.Table).Add(float64(event.Rows))
+	}
+}
+
+// ErrorCode is used to get MySQL error number as label value
+//
+// return "unknown" when error is not MySQL error
+func ErrorCode(err error) string {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return strconv.Itoa(int(mysqlErr.Number))
+	}
+	return "unknown"
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_update_bulk/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	collector := NewCollector("bulk")
+
+	events := []db.QueryEvent{
+		{Operation: "UpdateBulk", Table: "user", Rows: 100},
+		{Operation: "UpdateBulk", Table: "user", Page: 1, Rows: 100, Duration: time.Millisecond, WaitTime: time.Millisecond},
+		{Operation: "UpdateBulk", Table: "user", Page: 2, Rows: 50, Duration: time.Millisecond},
+		{Operation: "UpdateBulk", Table: "user", Page: 3, Rows: 10, Err: &mysql.MySQLError{Number: 1213, Message: "Deadlock"}},
+		{Operation: "Delete", Table: "user", Err: errors.New("failed")},
+	}
+	for index := range events {
+		event := &events[index]
+		collector.BeforeQuery(ctx, event)
+		if index == 1 {
+			assert.Equal(t, float64(1), testutil.ToFloat64(collector.pagesInFlight.WithLabelValues("UpdateBulk", "user")))
+		}
+		collector.AfterQuery(ctx, event)
+	}
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(collector.pagesInFlight.WithLabelValues("UpdateBulk", "user")))
+	assert.Equal(t, 2, testutil.CollectAndCount(collector, "bulk_page_duration_seconds", "bulk_semaphore_wait_seconds"))
+
+	expected := `
+		# HELP bulk_errors_total Total failed queries by MySQL error code.
+		# TYPE bulk_errors_total counter
+		bulk_errors_total{code="1213",operation="UpdateBulk",table="user"} 1
+		bulk_errors_total{code="unknown",operation="Delete",table="user"} 1
+		# HELP bulk_queries_total Total executed queries.
+		# TYPE bulk_queries_total counter
+		bulk_queries_total{operation="Delete",table="user"} 1
+		bulk_queries_total{operation="UpdateBulk",table="user"} 4
+		# HELP bulk_rows_total Total rows successfully processed.
+		# TYPE bulk_rows_total counter
+		bulk_rows_total{operation="UpdateBulk",table="user"} 150
+	`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "bulk_errors_total", "bulk_queries_total", "bulk_rows_total")
+	assert.Nil(t, err)
+}
+
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{err: &mysql.MySQLError{Number: 1062}, expected: "1062"},
+		{err: fmt.Errorf("error when update page 1: %w", &mysql.MySQLError{Number: 1205}), expected: "1205"},
+		{err: errors.New("failed"), expected: "unknown"},
+	}
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+			assert.Equal(t, testCase.expected, ErrorCode(testCase.err))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"go_update_bulk/db"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "go_update_bulk/db"
+
+const (
+	TableKey        = attribute.Key("db.sql.table")
+	StatementKey    = attribute.Key("db.statement")
+	RowsAffectedKey = attribute.Key("db.rows_affected")
+	StrategyKey     = attribute.Key("bulk.strategy")
+	RowsKey         = attribute.Key("bulk.rows")
+	PagesKey        = attribute.Key("bulk.pages")
+	PageKey         = attribute.Key("bulk.page")
+	PlaceholdersKey = attribute.Key("bulk.placeholders")
+)
+
+// Hook is used to trace db.SQL using OpenTelemetry
+//
+// each bulk operation has its own span, with child span for every page query
+//
+// the operation span is a child of the span found in caller's context (e.g. CreateBulkContext)
+type Hook struct {
+	tracer trace.Tracer
+}
+
+var _ db.Hook = &Hook{}
+var _ db.OperationHook = &Hook{}
+
+func NewHook(provider trace.TracerProvider) *Hook {
+	return &Hook{tracer: provider.Tracer(instrumentationName)}
+}
+
+func (h *Hook) BeforeOperation(ctx context.Context, event *db.OperationEvent) context.Context {
+	ctx, _ = h.tracer.Start(ctx, event.Operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.StartTime),
+		trace.WithAttributes(
+			TableKey.String(event.Table),
+			StrategyKey.String(event.Strategy),
+			RowsKey.Int(event.Rows),
+			PagesKey.Int(event.Pages),
+		),
+	)
+	return ctx
+}
+
+func (h *Hook) AfterOperation(ctx context.Context, event *db.OperationEvent) {
+	span := trace.SpanFromContext(ctx)
+	end(span, event.Err)
+}
+
+func (h *Hook) BeforeQuery(ctx context.Context, event *db.QueryEvent) context.Context {
+	name := event.Operation
+	attributes := []attribute.KeyValue{
+		TableKey.String(event.Table),
+		StatementKey.String(event.Query),
+		RowsKey.Int(event.Rows),
+		PlaceholdersKey.Int(event.Placeholders),
+	}
+	if event.Strategy != "" {
+		attributes = append(attributes, StrategyKey.String(event.Strategy))
+	}
+	if event.Page > 0 {
+		name = event.Operation + " page"
+		attributes = append(attributes, PageKey.Int(event.Page))
+	}
+	ctx, _ = h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.StartTime),
+		trace.WithAttributes(attributes...),
+	)
+	return ctx
+}
+
+func (h *Hook) AfterQuery(ctx context.Context, event *db.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(RowsAffectedKey.Int64(event.RowsAffected))
+	end(span, event.Err)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"go_update_bulk/db"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	hook := NewHook(provider)
+
+	// Caller span
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "caller")
+
+	op := &db.OperationEvent{Operation: "UpdateBulk", Strategy: "case", Table: "user", Rows: 3, Pages: 2, StartTime: time.Now()}
+	opCtx := hook.BeforeOperation(ctx, op)
+
+	pages := []*db.QueryEvent{
+		{Operation: "UpdateBulk", Strategy: "case", Table: "user", Page: 1, Rows: 2, Placeholders: 14, Query: "UPDATE user", StartTime: time.Now()},
+		{Operation: "UpdateBulk", Strategy: "case", Table: "user", Page: 2, Rows: 1, Placeholders: 7, Query: "UPDATE user", StartTime: time.Now(), Err: errors.New("failed")},
+	}
+	for _, page := range pages {
+		queryCtx := hook.BeforeQuery(opCtx, page)
+		hook.AfterQuery(queryCtx, page)
+	}
+
+	op.Err = errors.New("failed")
+	hook.AfterOperation(opCtx, op)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 4)
+
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		if span.Name == "UpdateBulk page" {
+			byName[span.Name+attributeValue(span.Attributes, PageKey).Emit()] = span
+			continue
+		}
+		byName[span.Name] = span
+	}
+
+	caller := byName["caller"]
+	operation := byName["UpdateBulk"]
+	page1 := byName["UpdateBulk page1"]
+	page2 := byName["UpdateBulk page2"]
+
+	assert.Equal(t, caller.SpanContext.SpanID(), operation.Parent.SpanID())
+	assert.Equal(t, operation.SpanContext.SpanID(), page1.Parent.SpanID())
+	assert.Equal(t, operation.SpanContext.SpanID(), page2.Parent.SpanID())
+	assert.Equal(t, caller.SpanContext.TraceID(), page2.SpanContext.TraceID())
+
+	assert.Equal(t, "user", attributeValue(operation.Attributes, TableKey).AsString())
+	assert.Equal(t, "case", attributeValue(operation.Attributes, StrategyKey).AsString())
+	assert.Equal(t, int64(2), attributeValue(operation.Attributes, PagesKey).AsInt64())
+	assert.Equal(t, codes.Error, operation.Status.Code)
+
+	assert.Equal(t, "case", attributeValue(page1.Attributes, StrategyKey).AsString())
+	assert.Equal(t, int64(2), attributeValue(page1.Attributes, RowsKey).AsInt64())
+	assert.Equal(t, int64(14), attributeValue(page1.Attributes, PlaceholdersKey).AsInt64())
+	assert.Equal(t, codes.Unset, page1.Status.Code)
+	assert.Equal(t, codes.Error, page2.Status.Code)
+}
+
+func attributeValue(attributes []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, attr := range attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column is definition of table column introspected using ColumnsQuery
+//
+// ColumnType is full type of the column, e.g. int(11) unsigned or enum('a','b'), Postgres uses DataType
+type Column struct {
+	Name       string `db:"column_name"`
+	DataType   string `db:"data_type"`
+	ColumnType string `db:"column_type"`
+	IsNullable string `db:"is_nullable"`
+	MaxLength  *int64 `db:"character_maximum_length"`
+	Precision  *int64 `db:"numeric_precision"`
+	Scale      *int64 `db:"numeric_scale"`
+}
+
+// Nullable is used to check whether the column accepts NULL
+func (c Column) Nullable() bool {
+	return strings.EqualFold(c.IsNullable, "YES")
+}
+
+// integerBits is size of integer types, MySQL and Postgres names
+var integerBits = map[string]uint{
+	"tinyint":   8,
+	"smallint":  16,
+	"mediumint": 24,
+	"int":       32,
+	"integer":   32,
+	"bigint":    64,
+}
+
+var decimalTypes = map[string]bool{
+	"decimal":          true,
+	"numeric":          true,
+	"float":            true,
+	"double":           true,
+	"real":             true,
+	"double precision": true,
+}
+
+var textTypes = map[string]bool{
+	"char":              true,
+	"varchar":           true,
+	"tinytext":          true,
+	"text":              true,
+	"mediumtext":        true,
+	"longtext":          true,
+	"character":         true,
+	"character varying": true,
+}
+
+// Check is used to validate value against nullability, length, numeric range and enum values of the column
+//
+// numeric string of numeric column is valid, when coerce is true it is converted to number,
+// except decimal column that keeps the exact decimal string, and number of text column is converted to string, so the returned value may differ from value
+//
+// Expr and types that are not known by the column type are returned as it is
+func (c Column) Check(value any, coerce bool) (any, error) {
+	if _, ok := value.(Expr); ok {
+		return value, nil
+	}
+	resolved, err := resolveValue(value)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == nil {
+		if !c.Nullable() {
+			return nil, fmt.Errorf("null is not allowed")
+		}
+		return value, nil
+	}
+
+	dataType := strings.ToLower(c.DataType)
+	if bits, ok := integerBits[dataType]; ok {
+		return c.checkInteger(value, resolved, bits, coerce)
+	}
+	if decimalTypes[dataType] {
+		return c.checkDecimal(value, resolved, coerce)
+	}
+	if textTypes[dataType] {
+		return c.checkText(value, resolved, coerce)
+	}
+	if dataType == "enum" {
+		return value, c.checkEnum(resolved)
+	}
+	return value, nil
+}
+
+func (c Column) checkInteger(value, resolved any, bits uint, coerce bool) (any, error) {
+	var number *big.Int
+	converted := false
+	rv := reflect.ValueOf(resolved)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number = big.NewInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number = new(big.Int).SetUint64(rv.Uint())
+	case reflect.Bool:
+		return value, nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v is not an integer", f)
+		}
+		number, _ = big.NewFloat(f).Int(nil)
+	case reflect.String:
+		parsed, ok := new(big.Int).SetString(strings.TrimSpace(rv.String()), 10)
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not an integer", rv.String())
+		}
+		number, converted = parsed, coerce
+	default:
+		return value, nil
+	}
+
+	min, max := new(big.Int), new(big.Int)
+	if strings.Contains(strings.ToLower(c.ColumnType), "unsigned") {
+		max.Lsh(big.NewInt(1), bits).Sub(max, big.NewInt(1))
+	} else {
+		min.Lsh(big.NewInt(1), bits-1).Neg(min)
+		max.Lsh(big.NewInt(1), bits-1).Sub(max, big.NewInt(1))
+	}
+	if number.Cmp(min) < 0 || number.Cmp(max) > 0 {
+		return nil, fmt.Errorf("%s is out of range [%s, %s]", number, min, max)
+	}
+	if converted {
+		if number.IsInt64() {
+			return number.Int64(), nil
+		}
+		return number.Uint64(), nil
+	}
+	return value, nil
+}
+
+// checkDecimal is used to check numeric range exactly using big.Rat, so large decimal doesn't lose precision,
+// coerced string of decimal column stays exact decimal string, only float column gets float64
+func (c Column) checkDecimal(value, resolved any, coerce bool) (any, error) {
+	dataType := strings.ToLower(c.DataType)
+	fixed := dataType == "decimal" || dataType == "numeric"
+
+	number := new(big.Rat)
+	rv := reflect.ValueOf(resolved)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number.SetInt64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number.SetUint64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("%v is not a number", f)
+		}
+		number.SetFloat64(f)
+	case reflect.String:
+		text := strings.TrimSpace(rv.String())
+		if _, ok := number.SetString(text); !ok || strings.Contains(text, "/") {
+			return nil, fmt.Errorf("'%s' is not a number", rv.String())
+		}
+		if coerce && fixed {
+			value = text
+		} else if coerce {
+			parsed, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' is not a number: %w", rv.String(), err)
+			}
+			value = parsed
+		}
+	default:
+		return value, nil
+	}
+
+	// only fixed point has range, integer digits are precision minus scale
+	if fixed && c.Precision != nil {
+		scale := int64(0)
+		if c.Scale != nil {
+			scale = *c.Scale
+		}
+		digits := *c.Precision - scale
+		limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(digits), nil)
+		if new(big.Rat).Abs(number).Cmp(new(big.Rat).SetInt(limit)) >= 0 {
+			return nil, fmt.Errorf("%s is out of range, maximum %d digits before decimal point", number.FloatString(int(scale)), digits)
+		}
+	}
+	return value, nil
+}
+
+func (c Column) checkText(value, resolved any, coerce bool) (any, error) {
+	var text string
+	switch v := resolved.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		rv := reflect.ValueOf(resolved)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			text = fmt.Sprint(resolved)
+			if coerce {
+				value = text
+			}
+		default:
+			return value, nil
+		}
+	}
+
+	if c.MaxLength != nil {
+		if length := utf8.RuneCountInString(text); int64(length) > *c.MaxLength {
+			return nil, fmt.Errorf("length %d is more than %d", length, *c.MaxLength)
+		}
+	}
+	return value, nil
+}
+
+func (c Column) checkEnum(resolved any) error {
+	text, ok := resolved.(string)
+	if !ok {
+		return nil
+	}
+	values := c.EnumValues()
+	for _, v := range values {
+		if v == text {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' is not one of %v", text, values)
+}
+
+// EnumValues is used to get allowed values of MySQL enum column, e.g. enum('a','b') is [a b]
+func (c Column) EnumValues() []string {
+	columnType := c.ColumnType
+	start, end := strings.Index(columnType, "("), strings.LastIndex(columnType, ")")
+	if !strings.HasPrefix(strings.ToLower(columnType), "enum") || start < 0 || end < start {
+		return nil
+	}
+
+	values := []string{}
+	for _, part := range strings.Split(columnType[start+1:end], "','") {
+		part = strings.TrimSuffix(strings.TrimPrefix(part, "'"), "'")
+		values = append(values, strings.ReplaceAll(part, "''", "'"))
+	}
+	return values
+}
+
+// resolveValue is used to get the value that is sent to the driver, e.g. value of pointer or driver.Valuer
+func resolveValue(value any) (any, error) {
+	for {
+		if value == nil {
+			return nil, nil
+		}
+		if valuer, ok := value.(driver.Valuer); ok {
+			v, err := valuer.Value()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := v.(driver.Valuer); ok {
+				return v, nil
+			}
+			value = v
+			continue
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Ptr {
+			return value, nil
+		}
+		if rv.IsNil() {
+			return nil, nil
+		}
+		value = rv.Elem().Interface()
+	}
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnCheck(t *testing.T) {
+	type testCase struct {
+		column Column
+		value  any
+		coerce bool
+		result any
+	}
+
+	length := int64(5)
+	precision, scale := int64(5), int64(2)
+	widePrecision, wideScale := int64(30), int64(10)
+	age := Column{Name: "age", DataType: "tinyint", ColumnType: "tinyint(3) unsigned", IsNullable: "NO"}
+	name := Column{Name: "name", DataType: "varchar", ColumnType: "varchar(5)", IsNullable: "YES", MaxLength: &length}
+	price := Column{Name: "price", DataType: "decimal", ColumnType: "decimal(5,2)", IsNullable: "NO", Precision: &precision, Scale: &scale}
+	amount := Column{Name: "amount", DataType: "decimal", ColumnType: "decimal(30,10)", IsNullable: "NO", Precision: &widePrecision, Scale: &wideScale}
+	rate := Column{Name: "rate", DataType: "double", ColumnType: "double", IsNullable: "NO"}
+	status := Column{Name: "status", DataType: "enum", ColumnType: "enum('active','it''s')", IsNullable: "NO"}
+
+	t.Run("success", func(t *testing.T) {
+		nickname := "Nick"
+		testCases := []testCase{
+			{column: age, value: 255, result: 255},
+			{column: age, value: "12", result: "12"},
+			{column: age, value: " 12", coerce: true, result: int64(12)},
+			{column: age, value: 12.0, result: 12.0},
+			{column: name, value: nil, result: nil},
+			{column: name, value: sql.NullString{}, result: sql.NullString{}},
+			{column: name, value: "ÀÁÂÃÄ", result: "ÀÁÂÃÄ"},
+			{column: name, value: &nickname, result: &nickname},
+			{column: name, value: 123, coerce: true, result: "123"},
+			{column: price, value: 999.99, result: 999.99},
+			{column: price, value: " 10.5", coerce: true, result: "10.5"},
+			{column: amount, value: "12345678901234567.1234567891", coerce: true, result: "12345678901234567.1234567891"},
+			{column: amount, value: "-99999999999999999999.9999999999", result: "-99999999999999999999.9999999999"},
+			{column: rate, value: "10.5", coerce: true, result: 10.5},
+			{column: status, value: "it's", result: "it's"},
+			{column: age, value: NewExpr("age + 1", nil), result: NewExpr("age + 1", nil)},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				result, err := testCase.column.Check(testCase.value, testCase.coerce)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.result, result)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		testCases := []testCase{
+			{column: age, value: nil},
+			{column: age, value: 256},
+			{column: age, value: -1},
+			{column: age, value: "twelve", coerce: true},
+			{column: age, value: 1.5},
+			{column: name, value: "ÀÁÂÃÄÅ"},
+			{column: name, value: 123456},
+			{column: price, value: 1000},
+			{column: price, value: "ten"},
+			{column: price, value: "1/2"},
+			{column: price, value: "1000.001"},
+			{column: amount, value: "100000000000000000000", coerce: true},
+			{column: status, value: "inactive"},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				_, err := testCase.column.Check(testCase.value, testCase.coerce)
+				assert.NotNil(t, err)
+			})
+		}
+	})
+}
+
+func TestColumnEnumValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b,c", "it's"}, Column{ColumnType: "enum('a','b,c','it''s')"}.EnumValues())
+	assert.Nil(t, Column{ColumnType: "varchar(5)"}.EnumValues())
+}
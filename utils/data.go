@@ -1,6 +1,9 @@
 package utils
 
-import "math"
+import (
+	"math"
+	"strings"
+)
 
 func PagedData[T any](arr []T, pageSize int) [][]T {
 	pagedData := [][]T{}
@@ -15,3 +18,35 @@ func PagedData[T any](arr []T, pageSize int) [][]T {
 	}
 	return pagedData
 }
+
+// GroupByFields is used to group data that have the same fields, keeping the order of the first appearance
+//
+// data with nil fields removed (e.g. StructToMap with removeNil) may have different fields for every data,
+// unset Optional field is removed, see ResolveOptional
+func GroupByFields(data []map[string]any) [][]map[string]any {
+	groups := [][]map[string]any{}
+	indexes := map[string]int{}
+	for _, item := range data {
+		item = ResolveOptional(item)
+		key := strings.Join(SortMapKeys(item), ",")
+		index, ok := indexes[key]
+		if !ok {
+			index = len(groups)
+			indexes[key] = index
+			groups = append(groups, []map[string]any{})
+		}
+		groups[index] = append(groups[index], item)
+	}
+	return groups
+}
+
+// MaxFieldSize is the largest number of fields in single data
+func MaxFieldSize(data []map[string]any) int {
+	size := 0
+	for _, item := range data {
+		if len(item) > size {
+			size = len(item)
+		}
+	}
+	return size
+}
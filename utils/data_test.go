@@ -44,3 +44,21 @@ func TestOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupByFields(t *testing.T) {
+	data := []map[string]any{
+		{"id": 1, "name": "Name1"},
+		{"id": 2},
+		{"name": "Name3", "id": 3},
+		{"id": 4, "age": 4},
+		{"id": 5},
+	}
+	groups := GroupByFields(data)
+	assert.Equal(t, [][]map[string]any{
+		{{"id": 1, "name": "Name1"}, {"name": "Name3", "id": 3}},
+		{{"id": 2}, {"id": 5}},
+		{{"id": 4, "age": 4}},
+	}, groups)
+	assert.Equal(t, 2, MaxFieldSize(data))
+	assert.Equal(t, 0, MaxFieldSize(nil))
+}
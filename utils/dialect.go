@@ -0,0 +1,34 @@
+package utils
+
+// Dialect is SQL database flavour that queries are built for
+//
+// MySQL is the default (zero value)
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case Postgres:
+		return "postgres"
+	}
+	return "unknown"
+}
+
+// DriverName is database/sql driver name used to connect to the dialect
+//
+// driver of the dialect need to be imported first, only mysql imported by default
+func (d Dialect) DriverName() string {
+	return d.String()
+}
+
+// SupportsUpdateJoin is used to check whether the dialect can update table by joining another table or VALUES,
+// e.g. UPDATE t JOIN s USING (id) SET ...
+func (d Dialect) SupportsUpdateJoin() bool {
+	return d == MySQL
+}
@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect(t *testing.T) {
+	var dialect Dialect
+	assert.Equal(t, MySQL, dialect)
+	assert.Equal(t, "mysql", MySQL.DriverName())
+	assert.Equal(t, "postgres", Postgres.DriverName())
+	assert.Equal(t, "unknown", Dialect(99).String())
+	assert.True(t, MySQL.SupportsUpdateJoin())
+	assert.False(t, Postgres.SupportsUpdateJoin())
+}
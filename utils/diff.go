@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiffRow is used to get fields of next that are different from current row selected from database,
+// keyEdits are always kept so the result can be used by BulkUpdateQuery
+//
+// return nil when nothing is changed, Expr and field that is not selected are always treated as changed
+func DiffRow(current, next map[string]any, keyEdits []string) map[string]any {
+	keys := make(map[string]bool, len(keyEdits))
+	for _, key := range keyEdits {
+		keys[key] = true
+	}
+
+	next = ResolveOptional(next)
+	diff := map[string]any{}
+	changed := false
+	for field, value := range next {
+		if keys[field] {
+			diff[field] = value
+			continue
+		}
+		if currentValue, ok := current[field]; ok && SameValue(currentValue, value) {
+			continue
+		}
+		diff[field] = value
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return diff
+}
+
+// SameValue is used to compare value selected from database with value that will be sent,
+// both are compared using their text representation, e.g. []byte("1") and int 1 are the same,
+// except time that is compared with parsed text, so DATETIME(6) text with trailing zeros is the same time
+//
+// value that can't be compared, e.g. Expr, is never the same
+func SameValue(current, next any) bool {
+	if same, ok := sameTime(current, next); ok {
+		return same
+	}
+	currentText, currentNull, ok := valueText(current)
+	if !ok {
+		return false
+	}
+	nextText, nextNull, ok := valueText(next)
+	if !ok {
+		return false
+	}
+	if currentNull || nextNull {
+		return currentNull == nextNull
+	}
+	return currentText == nextText
+}
+
+// RowKey is used to identify row using values of keyEdits, e.g. to match selected rows with data
+func RowKey(row map[string]any, keyEdits []string) (string, error) {
+	parts := make([]string, 0, len(keyEdits))
+	for _, key := range keyEdits {
+		value, ok := row[key]
+		if !ok {
+			return "", fmt.Errorf("key '%s' not found", key)
+		}
+		text, null, ok := valueText(value)
+		if !ok || null {
+			return "", fmt.Errorf("key '%s' can't be compared", key)
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// sameTime is used to compare time with time or text of the database in UTC, ok is false when neither is time
+func sameTime(current, next any) (same bool, ok bool) {
+	currentValue, err := resolveValue(current)
+	if err != nil {
+		return false, false
+	}
+	nextValue, err := resolveValue(next)
+	if err != nil {
+		return false, false
+	}
+	currentTime, currentOK := currentValue.(time.Time)
+	nextTime, nextOK := nextValue.(time.Time)
+	switch {
+	case currentOK && nextOK:
+		return currentTime.Equal(nextTime), true
+	case currentOK:
+		nextTime, nextOK = parseTimeText(nextValue)
+	case nextOK:
+		currentTime, currentOK = parseTimeText(currentValue)
+	default:
+		return false, false
+	}
+	if !currentOK || !nextOK {
+		return false, true
+	}
+	return currentTime.Equal(nextTime), true
+}
+
+// parseTimeText is used to parse DATETIME or DATE text, fractional second of any length is accepted
+func parseTimeText(value any) (time.Time, bool) {
+	text, null, ok := valueText(value)
+	if !ok || null {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, text, time.UTC); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// valueText is text representation of value, the same as database returns it using text protocol
+func valueText(value any) (text string, null bool, ok bool) {
+	if _, isExpr := value.(Expr); isExpr {
+		return "", false, false
+	}
+	resolved, err := resolveValue(value)
+	if err != nil {
+		return "", false, false
+	}
+
+	switch v := resolved.(type) {
+	case nil:
+		return "", true, true
+	case string:
+		return v, false, true
+	case []byte:
+		if v == nil {
+			return "", true, true
+		}
+		return string(v), false, true
+	case bool:
+		if v {
+			return "1", false, true
+		}
+		return "0", false, true
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05.999999"), false, true
+	}
+
+	rv := reflect.ValueOf(resolved)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), false, true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), false, true
+	case reflect.String:
+		return rv.String(), false, true
+	}
+	return "", false, false
+}
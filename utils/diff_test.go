@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRow(t *testing.T) {
+	type testCase struct {
+		current map[string]any
+		next    map[string]any
+		diff    map[string]any
+	}
+
+	testCases := []testCase{
+		{
+			current: map[string]any{"id": []byte("1"), "name": []byte("Name1"), "age": []byte("20")},
+			next:    map[string]any{"id": 1, "name": "Name1", "age": 20},
+			diff:    nil,
+		},
+		{
+			current: map[string]any{"id": int64(1), "name": []byte("Name1"), "age": nil},
+			next:    map[string]any{"id": 1, "name": "Name2", "age": Null[int]()},
+			diff:    map[string]any{"id": 1, "name": "Name2"},
+		},
+		{
+			current: map[string]any{"id": int64(1), "name": "Name1", "age": int64(20)},
+			next:    map[string]any{"id": 1, "name": Optional[string]{}, "age": nil},
+			diff:    map[string]any{"id": 1, "age": nil},
+		},
+		{
+			current: map[string]any{"id": int64(1), "stock": int64(5)},
+			next:    map[string]any{"id": 1, "stock": NewExpr("stock - 1", nil)},
+			diff:    map[string]any{"id": 1, "stock": NewExpr("stock - 1", nil)},
+		},
+		{
+			current: map[string]any{"id": int64(1)},
+			next:    map[string]any{"id": 1, "name": "Name1"},
+			diff:    map[string]any{"id": 1, "name": "Name1"},
+		},
+	}
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+			diff := DiffRow(testCase.current, testCase.next, []string{"id"})
+			assert.Equal(t, testCase.diff, diff)
+		})
+	}
+}
+
+func TestSameValue(t *testing.T) {
+	createdAt := time.Date(2023, 1, 2, 10, 4, 5, 0, time.FixedZone("WIB", 7*60*60))
+	name := "Name"
+
+	assert.True(t, SameValue([]byte("2023-01-02 03:04:05"), createdAt))
+	assert.True(t, SameValue(createdAt.UTC(), createdAt))
+	assert.True(t, SameValue([]byte("2023-01-02 03:04:05.000000"), createdAt))
+	assert.True(t, SameValue(time.Date(2024, 1, 1, 10, 0, 0, 500000000, time.UTC), "2024-01-01 10:00:00.500000"))
+	assert.True(t, SameValue([]byte("2023-01-02"), time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, SameValue([]byte("2023-01-02 03:04:05.000001"), createdAt))
+	assert.False(t, SameValue(nil, createdAt))
+	assert.False(t, SameValue([]byte("not a time"), createdAt))
+	assert.True(t, SameValue([]byte("1"), true))
+	assert.True(t, SameValue([]byte("1.5"), 1.5))
+	assert.True(t, SameValue("Name", &name))
+	assert.True(t, SameValue(nil, sql.NullString{}))
+	assert.False(t, SameValue([]byte(""), nil))
+	assert.False(t, SameValue([]byte("10.50"), 10.5))
+	assert.False(t, SameValue(int64(1), []int{1}))
+}
+
+func TestRowKey(t *testing.T) {
+	selected, err := RowKey(map[string]any{"code": []byte("A"), "id": int64(1)}, []string{"code", "id"})
+	assert.Nil(t, err)
+	data, err := RowKey(map[string]any{"code": "A", "id": 1, "name": "Name1"}, []string{"code", "id"})
+	assert.Nil(t, err)
+	assert.Equal(t, selected, data)
+
+	_, err = RowKey(map[string]any{"id": 1}, []string{"code"})
+	assert.NotNil(t, err)
+
+	_, err = RowKey(map[string]any{"id": nil}, []string{"id"})
+	assert.NotNil(t, err)
+}
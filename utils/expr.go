@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Expr is raw SQL fragment used as new value of a column, binds are named placeholders used by the fragment
+//
+// e.g. NewExpr("stock - :n", map[string]any{"n": 2}) or NewExpr("NOW()", nil)
+//
+// the fragment is inlined as it is, so it must never be built from user input, use binds instead
+type Expr struct {
+	SQL   string
+	Binds map[string]any
+}
+
+// NewExpr is used to create SQL expression value
+func NewExpr(sql string, binds map[string]any) Expr {
+	return Expr{SQL: sql, Binds: binds}
+}
+
+var exprBindRegex = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// inline is used to rename every bind of the expression using prefix,
+// so the same expression can be used in many places of single query
+//
+// sqlx reads :: as escaped colon, so type cast e.g. price::numeric is escaped to pass through sqlx,
+// and bind followed by cast e.g. :v::int is wrapped in parentheses because sqlx can't end bind name at colon
+func (e Expr) inline(prefix string) (string, map[string]any, error) {
+	if e.SQL == "" {
+		return "", nil, errors.New("expression is empty")
+	}
+	binds := map[string]any{}
+	used := map[string]bool{}
+	result := []byte{}
+	last := 0
+	for _, match := range exprBindRegex.FindAllStringSubmatchIndex(e.SQL, -1) {
+		start, end := match[0], match[1]
+		// skip type cast, e.g. value::int
+		if start > 0 && e.SQL[start-1] == ':' {
+			continue
+		}
+		name := e.SQL[match[2]:match[3]]
+		value, ok := e.Binds[name]
+		if !ok {
+			return "", nil, fmt.Errorf("bind '%s' of expression not found", name)
+		}
+		bindKey := fmt.Sprintf("%s_%s", prefix, name)
+		result = append(result, escapeCast(e.SQL[last:start])...)
+		if strings.HasPrefix(e.SQL[end:], ":") {
+			result = append(result, fmt.Sprintf("(:%s)", bindKey)...)
+		} else {
+			result = append(result, fmt.Sprintf(":%s", bindKey)...)
+		}
+		binds[bindKey] = value
+		used[name] = true
+		last = end
+	}
+	result = append(result, escapeCast(e.SQL[last:])...)
+
+	for name := range e.Binds {
+		if !used[name] {
+			return "", nil, fmt.Errorf("bind '%s' of expression not used", name)
+		}
+	}
+	return string(result), binds, nil
+}
+
+// escapeCast is used to escape :: of type cast, sqlx turns :::: back into ::
+func escapeCast(sql string) string {
+	return strings.ReplaceAll(sql, "::", "::::")
+}
+
+// HasExpr is used to get the first field of data that uses Expr
+func HasExpr(data map[string]any) (field string, ok bool) {
+	for _, key := range SortMapKeys(data) {
+		if _, ok := data[key].(Expr); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
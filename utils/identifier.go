@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxIdentifierLength is the maximum length of table or column name in MySQL
+const MaxIdentifierLength = 64
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ValidateIdentifier is used to reject column name that can't be used safely in query,
+// only letters, digits and underscore are allowed, name is quoted so it may start with digit
+//
+// dollar sign isn't allowed because bind names are built from column name and sqlx ends named bind at it
+func ValidateIdentifier(name string) error {
+	if len(name) > MaxIdentifierLength || !identifierRegex.MatchString(name) {
+		return fmt.Errorf("invalid identifier '%s'", name)
+	}
+	return nil
+}
+
+// ValidateTable is the same as ValidateIdentifier, but schema qualified table name is allowed, e.g. db.user
+func ValidateTable(table string) error {
+	parts := strings.Split(table, ".")
+	if len(parts) > 2 {
+		return fmt.Errorf("invalid identifier '%s'", table)
+	}
+	for _, part := range parts {
+		if err := ValidateIdentifier(part); err != nil {
+			return fmt.Errorf("invalid identifier '%s'", table)
+		}
+	}
+	return nil
+}
+
+// QuoteIdentifier is used to validate and quote column name,
+// e.g. `name` for MySQL and "name" for Postgres
+func (d Dialect) QuoteIdentifier(name string) (string, error) {
+	if err := ValidateIdentifier(name); err != nil {
+		return "", err
+	}
+	return d.quote(name), nil
+}
+
+// QuoteTable is used to validate and quote table name, every part of schema qualified name is quoted,
+// e.g. `db`.`user` for MySQL
+func (d Dialect) QuoteTable(table string) (string, error) {
+	if err := ValidateTable(table); err != nil {
+		return "", err
+	}
+	return d.quote(table), nil
+}
+
+// quote is used to quote identifier that already validated
+func (d Dialect) quote(name string) string {
+	mark := "`"
+	if d == Postgres {
+		mark = `"`
+	}
+	parts := strings.Split(name, ".")
+	for index, part := range parts {
+		parts[index] = mark + part + mark
+	}
+	return strings.Join(parts, ".")
+}
+
+func (d Dialect) quoteAll(names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, d.quote(name))
+	}
+	return result
+}
+
+// validateIdentifiers is used to validate table and every column name
+func validateIdentifiers(table string, columns ...string) error {
+	if err := ValidateTable(table); err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if err := ValidateIdentifier(column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFields is used to validate every field of data, e.g. keys of map decoded from user JSON
+func validateFields(data ...map[string]any) error {
+	for _, item := range data {
+		for key := range item {
+			if err := ValidateIdentifier(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	type testCase struct {
+		dialect Dialect
+		name    string
+		result  string
+	}
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{dialect: MySQL, name: "name", result: "`name`"},
+			{dialect: Postgres, name: "name", result: `"name"`},
+			{dialect: MySQL, name: "_created_at1", result: "`_created_at1`"},
+			{dialect: MySQL, name: "2fa_enabled", result: "`2fa_enabled`"},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				result, err := testCase.dialect.QuoteIdentifier(testCase.name)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.result, result)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		names := []string{"", "cost$", "user.name", "name`", "name = 1; DROP TABLE user; --", string(make([]byte, MaxIdentifierLength+1))}
+		for index, name := range names {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				_, err := MySQL.QuoteIdentifier(name)
+				assert.NotNil(t, err)
+			})
+		}
+	})
+}
+
+func TestQuoteTable(t *testing.T) {
+	type testCase struct {
+		dialect Dialect
+		table   string
+		result  string
+	}
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{dialect: MySQL, table: "user", result: "`user`"},
+			{dialect: MySQL, table: "db.user", result: "`db`.`user`"},
+			{dialect: Postgres, table: "public.user", result: `"public"."user"`},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				result, err := testCase.dialect.QuoteTable(testCase.table)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.result, result)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		tables := []string{"", "a.b.c", "db.", "user; DROP TABLE user"}
+		for index, table := range tables {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				_, err := MySQL.QuoteTable(table)
+				assert.NotNil(t, err)
+			})
+		}
+	})
+}
+
+func TestQueryRejectInvalidIdentifier(t *testing.T) {
+	_, _, err := UpdateQuery(MySQL, "user", map[string]any{"name = 1; DROP TABLE user; --": "x"}, map[string]any{"id": 1})
+	assert.NotNil(t, err)
+
+	_, _, err = CreateQuery(MySQL, "user`", map[string]any{"name": "x"})
+	assert.NotNil(t, err)
+
+	_, _, err = BulkUpdateQuery(MySQL, "user", []map[string]any{{"id": 1, "na me": "x"}}, []string{"id"})
+	assert.NotNil(t, err)
+}
+
+func TestQueryBindDigitIdentifier(t *testing.T) {
+	query, binds, err := BulkUpdateQuery(MySQL, "user", []map[string]any{{"id": 1, "2fa_enabled": true}}, []string{"id"})
+	assert.Nil(t, err)
+	_, args, err := sqlx.Named(query, binds)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{1, true, 1}, args)
+
+	query, binds, err = UpdateQuery(MySQL, "user", map[string]any{"2fa_enabled": true}, map[string]any{"id": 1})
+	assert.Nil(t, err)
+	_, args, err = sqlx.Named(query, binds)
+	assert.Nil(t, err)
+	assert.Equal(t, []any{true, 1}, args)
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadDataNull is NULL representation in LOAD DATA file, only valid when it is not enclosed
+const LoadDataNull = `\N`
+
+// LoadDataQuery to build LOAD DATA LOCAL INFILE query that reads rows from registered reader handler
+//
+// rows need to be written using LoadDataWriter, see mysql.RegisterReaderHandler
+//
+// LOAD DATA is only supported by MySQL, so identifiers are quoted using backticks
+func LoadDataQuery(table, readerName string, columns []string) (query string, err error) {
+	if table == "" {
+		return "", errors.New("table is empty")
+	}
+	if readerName == "" {
+		return "", errors.New("reader name is empty")
+	}
+	if len(columns) == 0 {
+		return "", errors.New("columns is empty")
+	}
+	if err := validateIdentifiers(table, columns...); err != nil {
+		return "", err
+	}
+	if err := ValidateIdentifier(readerName); err != nil {
+		return "", err
+	}
+	query = fmt.Sprintf(
+		`LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n' (%s)`,
+		readerName,
+		MySQL.quote(table),
+		strings.Join(MySQL.quoteAll(columns), ", "),
+	)
+	return query, nil
+}
+
+// LoadDataWriter is used to write rows as CSV that can be read by LoadDataQuery
+type LoadDataWriter struct {
+	w       *bufio.Writer
+	columns []string
+	loc     *time.Location
+}
+
+// NewLoadDataWriter is used to write rows in the same order of columns
+//
+// time is written in loc, it should be the same as loc of the connection (default UTC)
+func NewLoadDataWriter(w io.Writer, columns []string, loc *time.Location) *LoadDataWriter {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &LoadDataWriter{w: bufio.NewWriter(w), columns: columns, loc: loc}
+}
+
+// Write is used to write single row, missing column is written as NULL
+func (l *LoadDataWriter) Write(row map[string]any) error {
+	for index, column := range l.columns {
+		if index > 0 {
+			if err := l.w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		field, err := l.encode(row[column])
+		if err != nil {
+			return fmt.Errorf("failed encode '%s': %w", column, err)
+		}
+		if _, err := l.w.WriteString(field); err != nil {
+			return err
+		}
+	}
+	return l.w.WriteByte('\n')
+}
+
+// Flush is used to write buffered rows to the underlying writer
+func (l *LoadDataWriter) Flush() error {
+	return l.w.Flush()
+}
+
+func (l *LoadDataWriter) encode(value any) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return LoadDataNull, nil
+	case string:
+		return quoteLoadData(v), nil
+	case []byte:
+		if v == nil {
+			return LoadDataNull, nil
+		}
+		return quoteLoadData(string(v)), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return quoteLoadData(v.In(l.loc).Format("2006-01-02 15:04:05.999999")), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return LoadDataNull, nil
+		}
+		return l.encode(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), nil
+	case reflect.String:
+		return quoteLoadData(rv.String()), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", value)
+}
+
+var loadDataEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x00", `\0`,
+	"\x1a", `\Z`,
+)
+
+// quoteLoadData is used to enclose field, so separator and NULL-like text are read as it is
+func quoteLoadData(s string) string {
+	return `"` + loadDataEscaper.Replace(s) + `"`
+}
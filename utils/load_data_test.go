@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDataQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query, err := LoadDataQuery("user", "load_user_1", []string{"age", "id", "name"})
+		assert.Nil(t, err)
+		expected := "LOAD DATA LOCAL INFILE 'Reader::load_user_1' INTO TABLE `user` CHARACTER SET utf8mb4 " +
+			`FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n' ` +
+			"(`age`, `id`, `name`)"
+		assert.Equal(t, expected, query)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, err := LoadDataQuery("", "load_user_1", []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = LoadDataQuery("user", "", []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = LoadDataQuery("user", "load_user_1", []string{})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLoadDataWriter(t *testing.T) {
+	type testCase struct {
+		row    map[string]any
+		result string
+	}
+
+	name := "Name"
+	var nilName *string
+	createdAt := time.Date(2023, 1, 2, 10, 4, 5, 123000, time.FixedZone("WIB", 7*60*60))
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{
+				row:    map[string]any{"id": 1, "name": "Name1", "value": 1.5},
+				result: "1,\"Name1\",1.5\n",
+			},
+			{
+				row:    map[string]any{"id": int64(2), "name": nil},
+				result: "2,\\N,\\N\n",
+			},
+			{
+				row:    map[string]any{"id": uint8(3), "name": "a,\"b\"\\c\nd", "value": true},
+				result: "3,\"a,\\\"b\\\"\\\\c\\nd\",1\n",
+			},
+			{
+				row:    map[string]any{"id": 4, "name": &name, "value": nilName},
+				result: "4,\"Name\",\\N\n",
+			},
+			{
+				row:    map[string]any{"id": 5, "name": sql.NullString{}, "value": createdAt},
+				result: "5,\\N,\"2023-01-02 03:04:05.000123\"\n",
+			},
+			{
+				row:    map[string]any{"id": 6, "name": "\\N", "value": []byte("raw")},
+				result: "6,\"\\\\N\",\"raw\"\n",
+			},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				var buf bytes.Buffer
+				writer := NewLoadDataWriter(&buf, []string{"id", "name", "value"}, time.UTC)
+				assert.Nil(t, writer.Write(testCase.row))
+				assert.Nil(t, writer.Flush())
+				assert.Equal(t, testCase.result, buf.String())
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := NewLoadDataWriter(&buf, []string{"id"}, nil)
+		err := writer.Write(map[string]any{"id": []int{1}})
+		assert.NotNil(t, err)
+	})
+}
@@ -10,3 +10,14 @@ func SortMapKeys[T any](m map[string]T) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+// PickFields is used to copy only the given fields of m, missing field is skipped
+func PickFields[T any](m map[string]T, fields []string) map[string]T {
+	result := make(map[string]T, len(fields))
+	for _, field := range fields {
+		if value, ok := m[field]; ok {
+			result[field] = value
+		}
+	}
+	return result
+}
@@ -12,3 +12,9 @@ func TestSortMapKeys(t *testing.T) {
 	actual := SortMapKeys(data)
 	assert.Equal(t, expected, actual)
 }
+
+func TestPickFields(t *testing.T) {
+	m := map[string]any{"id": 1, "name": "Name1", "version": 2}
+	assert.Equal(t, map[string]any{"id": 1, "version": 2}, PickFields(m, []string{"id", "version", "non_exists"}))
+	assert.Equal(t, map[string]any{}, PickFields(m, nil))
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"database/sql/driver"
+)
+
+type optionalState uint8
+
+const (
+	optionalUnset optionalState = iota
+	optionalNull
+	optionalSet
+)
+
+// Optional is tri-state value of a column, zero value is unset
+//
+// unset column is not touched by queries, null column is set to NULL and set column is set to the value,
+// e.g. struct { Name utils.Optional[string] `db:"name"` }
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+// Set is used to create Optional that sets the column to value
+func Set[T any](value T) Optional[T] {
+	return Optional[T]{value: value, state: optionalSet}
+}
+
+// Null is used to create Optional that sets the column to NULL
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+func (o Optional[T]) IsSet() bool {
+	return o.state == optionalSet
+}
+
+func (o Optional[T]) IsNull() bool {
+	return o.state == optionalNull
+}
+
+func (o Optional[T]) IsUnset() bool {
+	return o.state == optionalUnset
+}
+
+// Get is used to get the value, ok is false when it is null or unset
+func (o Optional[T]) Get() (value T, ok bool) {
+	return o.value, o.state == optionalSet
+}
+
+// Value is used to bind Optional directly as query argument, null and unset are bound as NULL
+func (o Optional[T]) Value() (driver.Value, error) {
+	if o.state != optionalSet {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(o.value)
+}
+
+func (o Optional[T]) get() any {
+	if o.state != optionalSet {
+		return nil
+	}
+	return o.value
+}
+
+type optional interface {
+	IsUnset() bool
+	get() any
+}
+
+// ResolveOptional is used to remove unset Optional fields of data and replace the others with their value
+//
+// data itself is returned when it doesn't have any Optional field
+func ResolveOptional(data map[string]any) map[string]any {
+	found := false
+	for _, value := range data {
+		if _, ok := value.(optional); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return data
+	}
+
+	result := make(map[string]any, len(data))
+	for key, value := range data {
+		if o, ok := value.(optional); ok {
+			if o.IsUnset() {
+				continue
+			}
+			value = o.get()
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// ResolveOptionals is used to resolve Optional fields of every data, see ResolveOptional
+func ResolveOptionals(data []map[string]any) []map[string]any {
+	result := make([]map[string]any, len(data))
+	for index, item := range data {
+		result[index] = ResolveOptional(item)
+	}
+	return result
+}
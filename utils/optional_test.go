@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptional(t *testing.T) {
+	t.Run("state", func(t *testing.T) {
+		var unset Optional[int]
+		assert.True(t, unset.IsUnset())
+		assert.True(t, Null[int]().IsNull())
+		assert.True(t, Set(0).IsSet())
+
+		value, ok := Set(5).Get()
+		assert.True(t, ok)
+		assert.Equal(t, 5, value)
+
+		_, ok = Null[int]().Get()
+		assert.False(t, ok)
+	})
+
+	t.Run("value", func(t *testing.T) {
+		value, err := Set(5).Value()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), value)
+
+		value, err = Null[string]().Value()
+		assert.Nil(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		data := map[string]any{"id": 1, "name": "Name1"}
+		assert.Equal(t, data, ResolveOptional(data))
+
+		data = map[string]any{"id": 1, "name": Null[string](), "age": Set(20), "address": Optional[string]{}}
+		assert.Equal(t, map[string]any{"id": 1, "name": nil, "age": 20}, ResolveOptional(data))
+
+		assert.Equal(t, []map[string]any{{"id": 1}, {"id": 2, "age": 3}}, ResolveOptionals([]map[string]any{
+			{"id": 1, "age": Optional[int]{}},
+			{"id": 2, "age": Set(3)},
+		}))
+	})
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// ColumnsQuery to build query that selects columns of the table from INFORMATION_SCHEMA, ordered by position,
+// selected columns can be scanned into Column
+//
+// table without schema is looked up in the current database (MySQL) or current schema (Postgres),
+// e.g. db.user is looked up in db
+func ColumnsQuery(dialect Dialect, table string) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	if err := ValidateTable(table); err != nil {
+		return "", map[string]any{}, err
+	}
+
+	// Postgres has no COLUMN_TYPE, enum and unsigned are MySQL only
+	columnType := "COLUMN_TYPE"
+	if dialect == Postgres {
+		columnType = "DATA_TYPE"
+	}
+	schema, binds := schemaCondition(dialect, table)
+	query = "SELECT COLUMN_NAME AS column_name, DATA_TYPE AS data_type, " + columnType + " AS column_type," +
+		" IS_NULLABLE AS is_nullable, CHARACTER_MAXIMUM_LENGTH AS character_maximum_length," +
+		" NUMERIC_PRECISION AS numeric_precision, NUMERIC_SCALE AS numeric_scale" +
+		" FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = " + schema +
+		" AND TABLE_NAME = :table_name ORDER BY ORDINAL_POSITION"
+	return query, binds, nil
+}
+
+// KeysQuery to build query that selects primary and unique keys of the table from INFORMATION_SCHEMA,
+// each row is a column of the key, columns of the same key are ordered by position
+//
+// selected columns are key_name, key_type (PRIMARY KEY or UNIQUE) and column_name
+func KeysQuery(dialect Dialect, table string) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	if err := ValidateTable(table); err != nil {
+		return "", map[string]any{}, err
+	}
+
+	schema, binds := schemaCondition(dialect, table)
+	query = "SELECT tc.CONSTRAINT_NAME AS key_name, tc.CONSTRAINT_TYPE AS key_type, kcu.COLUMN_NAME AS column_name" +
+		" FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc" +
+		" JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA" +
+		" AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME AND kcu.TABLE_NAME = tc.TABLE_NAME" +
+		" WHERE tc.TABLE_SCHEMA = " + schema + " AND tc.TABLE_NAME = :table_name AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE')" +
+		" ORDER BY tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION"
+	return query, binds, nil
+}
+
+// schemaCondition is used to get TABLE_SCHEMA and TABLE_NAME binds of already validated table
+func schemaCondition(dialect Dialect, table string) (schema string, binds map[string]any) {
+	binds = map[string]any{"table_name": table}
+	if index := strings.Index(table, "."); index >= 0 {
+		binds["table_schema"] = table[:index]
+		binds["table_name"] = table[index+1:]
+		return ":table_schema", binds
+	}
+	if dialect == Postgres {
+		return "current_schema()", binds
+	}
+	return "DATABASE()", binds
+}
+
+// NormalizeColumn is used to compare column names the same way as the dialect,
+// MySQL column names are case-insensitive, quoted Postgres column names are case-sensitive
+func (d Dialect) NormalizeColumn(name string) string {
+	if d == MySQL {
+		return strings.ToLower(name)
+	}
+	return name
+}
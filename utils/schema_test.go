@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnsQuery(t *testing.T) {
+	columnsPrefix := "SELECT COLUMN_NAME AS column_name, DATA_TYPE AS data_type, "
+	columnsSuffix := ", IS_NULLABLE AS is_nullable, CHARACTER_MAXIMUM_LENGTH AS character_maximum_length, " +
+		"NUMERIC_PRECISION AS numeric_precision, NUMERIC_SCALE AS numeric_scale FROM INFORMATION_SCHEMA.COLUMNS "
+	type testCase struct {
+		dialect Dialect
+		table   string
+		query   string
+		binds   map[string]any
+	}
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{
+				dialect: MySQL,
+				table:   "user",
+				query:   columnsPrefix + "COLUMN_TYPE AS column_type" + columnsSuffix + "WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = :table_name ORDER BY ORDINAL_POSITION",
+				binds:   map[string]any{"table_name": "user"},
+			},
+			{
+				dialect: Postgres,
+				table:   "user",
+				query:   columnsPrefix + "DATA_TYPE AS column_type" + columnsSuffix + "WHERE TABLE_SCHEMA = current_schema() AND TABLE_NAME = :table_name ORDER BY ORDINAL_POSITION",
+				binds:   map[string]any{"table_name": "user"},
+			},
+			{
+				dialect: MySQL,
+				table:   "db.user",
+				query:   columnsPrefix + "COLUMN_TYPE AS column_type" + columnsSuffix + "WHERE TABLE_SCHEMA = :table_schema AND TABLE_NAME = :table_name ORDER BY ORDINAL_POSITION",
+				binds:   map[string]any{"table_schema": "db", "table_name": "user"},
+			},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				query, binds, err := ColumnsQuery(testCase.dialect, testCase.table)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.query, query)
+				assert.Equal(t, testCase.binds, binds)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, _, err := ColumnsQuery(MySQL, "")
+		assert.NotNil(t, err)
+
+		_, _, err = ColumnsQuery(MySQL, "user'; --")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestKeysQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query, binds, err := KeysQuery(MySQL, "db.user")
+		assert.Nil(t, err)
+		expected := "SELECT tc.CONSTRAINT_NAME AS key_name, tc.CONSTRAINT_TYPE AS key_type, kcu.COLUMN_NAME AS column_name " +
+			"FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc " +
+			"JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA " +
+			"AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME AND kcu.TABLE_NAME = tc.TABLE_NAME " +
+			"WHERE tc.TABLE_SCHEMA = :table_schema AND tc.TABLE_NAME = :table_name AND tc.CONSTRAINT_TYPE IN ('PRIMARY KEY', 'UNIQUE') " +
+			"ORDER BY tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION"
+		assert.Equal(t, expected, query)
+		assert.Equal(t, map[string]any{"table_schema": "db", "table_name": "user"}, binds)
+
+		query, _, err = KeysQuery(Postgres, "user")
+		assert.Nil(t, err)
+		assert.Contains(t, query, "tc.TABLE_SCHEMA = current_schema()")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, _, err := KeysQuery(MySQL, "")
+		assert.NotNil(t, err)
+
+		_, _, err = KeysQuery(MySQL, "user'; --")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNormalizeColumn(t *testing.T) {
+	assert.Equal(t, "name", MySQL.NormalizeColumn("Name"))
+	assert.Equal(t, "Name", Postgres.NormalizeColumn("Name"))
+}
@@ -41,10 +41,25 @@ func BulkUpdateEstimateTotalField(dataSize, fieldSize, conditionSize int) int {
 	return fields + fieldCondition + whereCondition
 }
 
+// BulkUpdateValuesEstimateTotalField is to count all of fields/placeholders that created in BulkUpdateValuesQuery
+//
+// dataSize is length of data
+//
+// fieldSize is length of struct/map
+func BulkUpdateValuesEstimateTotalField(dataSize, fieldSize int) int {
+	return dataSize * fieldSize
+}
+
 // BulkUpdateQuery to build bulk update data SQL in single query
 //
-// keyEdits is key that used as conditional e.g []string{"id"}
-func BulkUpdateQuery(table string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
+// data may have different fields, CASE of the field only has data that have the field,
+// other data keep their current value, so unset Optional field is not touched
+//
+// Expr value is inlined into CASE of the data, its binds are renamed using field and data index e.g. :stock_0_n
+//
+// keyEdits is key that used as conditional e.g []string{"id"}, data is not modified,
+// so the same data can be used again e.g. by retry or audit after image
+func BulkUpdateQuery(dialect Dialect, table string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
 	emptyBinds := map[string]any{}
 	if table == "" {
 		return "", emptyBinds, errors.New("table is empty")
@@ -56,11 +71,18 @@ func BulkUpdateQuery(table string, data []map[string]any, keyEdits []string) (qu
 		return "", emptyBinds, errors.New("key edit is empty")
 	}
 	sort.Strings(keyEdits)
+	if err := validateIdentifiers(table, keyEdits...); err != nil {
+		return "", emptyBinds, err
+	}
+	if err := validateFields(data...); err != nil {
+		return "", emptyBinds, err
+	}
 
 	binds = map[string]any{}
 	columns := map[string]string{}
 	conditions := map[string][]string{}
 	for index, item := range data {
+		item = ResolveOptional(item)
 		condition := []string{}
 		for _, key := range keyEdits {
 			value, ok := item[key]
@@ -68,32 +90,51 @@ func BulkUpdateQuery(table string, data []map[string]any, keyEdits []string) (qu
 				return "", emptyBinds, fmt.Errorf("key '%s' found in the data number %d", key, index+1)
 			}
 			bindKey := fmt.Sprintf("%s_%d", key, index)
-			condition = append(condition, fmt.Sprintf("%s = :%s", key, bindKey))
+			condition = append(condition, fmt.Sprintf("%s = :%s", dialect.quote(key), bindKey))
 			conditions[key] = append(conditions[key], fmt.Sprintf(":%s", bindKey))
 			binds[bindKey] = value
-			delete(item, key)
 		}
 
+		// item may be the data itself, so keys are skipped instead of deleted
 		for key, value := range item {
+			if containsString(keyEdits, key) {
+				continue
+			}
 			bindKey := fmt.Sprintf("%s_%d", key, index)
-			binds[bindKey] = value
-			columns[key] = fmt.Sprintf("%s WHEN %s THEN %s", columns[key], strings.Join(condition, " AND "), fmt.Sprintf(":%s", bindKey))
+			result := fmt.Sprintf(":%s", bindKey)
+			if expr, ok := value.(Expr); ok {
+				exprQuery, exprBinds, err := expr.inline(bindKey)
+				if err != nil {
+					return "", emptyBinds, fmt.Errorf("failed build expression '%s' of the data number %d: %w", key, index+1, err)
+				}
+				result = exprQuery
+				for k, v := range exprBinds {
+					binds[k] = v
+				}
+			} else {
+				binds[bindKey] = value
+			}
+			columns[key] = fmt.Sprintf("%s WHEN %s THEN %s", columns[key], strings.Join(condition, " AND "), result)
 		}
 	}
 
+	if len(columns) == 0 {
+		return "", emptyBinds, errors.New("no field to update")
+	}
+
 	fieldQueries := []string{}
 	for _, key := range SortMapKeys(columns) {
-		fieldQueries = append(fieldQueries, fmt.Sprintf("%s = ( CASE %s ELSE %s END )", key, columns[key], key))
+		fieldQueries = append(fieldQueries, fmt.Sprintf("%s = ( CASE %s ELSE %s END )", dialect.quote(key), columns[key], dialect.quote(key)))
 	}
 
 	conditionQueries := []string{}
 	for _, key := range SortMapKeys(conditions) {
-		conditionQueries = append(conditionQueries, fmt.Sprintf("%s IN (%s)", key, strings.Join(conditions[key], ", ")))
+		conditionQueries = append(conditionQueries, fmt.Sprintf("%s IN (%s)", dialect.quote(key), strings.Join(conditions[key], ", ")))
 	}
 
 	query = fmt.Sprintf(
 		"UPDATE %s SET %s WHERE %s",
-		table,
+		dialect.quote(table),
 		strings.Join(fieldQueries, ", "),
 		strings.Join(conditionQueries, " AND "),
 	)
@@ -101,15 +142,371 @@ func BulkUpdateQuery(table string, data []map[string]any, keyEdits []string) (qu
 	return query, binds, nil
 }
 
+// BulkUpdateValuesQuery to build bulk update data SQL by joining the table with VALUES derived table
+//
+// need MySQL 8.0.19 or later, every data should have the same fields
+//
+// keyEdits is key that used as conditional e.g []string{"id"}
+func BulkUpdateValuesQuery(dialect Dialect, table string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
+	emptyBinds := map[string]any{}
+	if table == "" {
+		return "", emptyBinds, errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return "", emptyBinds, errors.New("data is empty")
+	}
+	if len(keyEdits) == 0 {
+		return "", emptyBinds, errors.New("key edit is empty")
+	}
+	sort.Strings(keyEdits)
+
+	if err := validateIdentifiers(table, keyEdits...); err != nil {
+		return "", emptyBinds, err
+	}
+	if err := validateFields(data...); err != nil {
+		return "", emptyBinds, err
+	}
+
+	const alias = "new_values"
+	data = ResolveOptionals(data)
+	columns := SortMapKeys(data[0])
+
+	binds = map[string]any{}
+	rows := []string{}
+	for index, item := range data {
+		if len(item) != len(columns) {
+			return "", emptyBinds, fmt.Errorf("fields of data number %d is different with the first data", index+1)
+		}
+		if field, ok := HasExpr(item); ok {
+			return "", emptyBinds, fmt.Errorf("expression '%s' of the data number %d is not supported", field, index+1)
+		}
+		placeholders := []string{}
+		for _, key := range columns {
+			value, ok := item[key]
+			if !ok {
+				return "", emptyBinds, fmt.Errorf("key '%s' not found in the data number %d", key, index+1)
+			}
+			bindKey := fmt.Sprintf("%s_%d", key, index)
+			placeholders = append(placeholders, fmt.Sprintf(":%s", bindKey))
+			binds[bindKey] = value
+		}
+		rows = append(rows, fmt.Sprintf("ROW(%s)", strings.Join(placeholders, ", ")))
+	}
+
+	conditions := []string{}
+	for _, key := range keyEdits {
+		if _, ok := data[0][key]; !ok {
+			return "", emptyBinds, fmt.Errorf("key '%s' not found in the data number 1", key)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s.%s = %s.%s", dialect.quote(table), dialect.quote(key), alias, dialect.quote(key)))
+	}
+
+	fields := []string{}
+	for _, key := range columns {
+		if containsString(keyEdits, key) {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s.%s = %s.%s", dialect.quote(table), dialect.quote(key), alias, dialect.quote(key)))
+	}
+	if len(fields) == 0 {
+		return "", emptyBinds, errors.New("no field to update")
+	}
+
+	query = fmt.Sprintf(
+		"UPDATE %s JOIN ( VALUES %s ) AS %s (%s) ON %s SET %s",
+		dialect.quote(table),
+		strings.Join(rows, ", "),
+		alias,
+		strings.Join(dialect.quoteAll(columns), ", "),
+		strings.Join(conditions, " AND "),
+		strings.Join(fields, ", "),
+	)
+	return query, binds, nil
+}
+
+// BulkUpsertQuery to build bulk update data SQL using INSERT ... ON DUPLICATE KEY UPDATE
+//
+// missing data will be inserted, every data should have the same fields
+//
+// keyEdits is primary/unique key that used to find duplicate e.g []string{"id"}
+func BulkUpsertQuery(dialect Dialect, table string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
+	emptyBinds := map[string]any{}
+	if table == "" {
+		return "", emptyBinds, errors.New("table is empty")
+	}
+	if len(data) == 0 {
+		return "", emptyBinds, errors.New("data is empty")
+	}
+	if len(keyEdits) == 0 {
+		return "", emptyBinds, errors.New("key edit is empty")
+	}
+
+	if err := validateIdentifiers(table, keyEdits...); err != nil {
+		return "", emptyBinds, err
+	}
+	if err := validateFields(data...); err != nil {
+		return "", emptyBinds, err
+	}
+
+	data = ResolveOptionals(data)
+	columns := SortMapKeys(data[0])
+	for _, key := range keyEdits {
+		if _, ok := data[0][key]; !ok {
+			return "", emptyBinds, fmt.Errorf("key '%s' not found in the data number 1", key)
+		}
+	}
+
+	binds = map[string]any{}
+	rows := []string{}
+	for index, item := range data {
+		if len(item) != len(columns) {
+			return "", emptyBinds, fmt.Errorf("fields of data number %d is different with the first data", index+1)
+		}
+		if field, ok := HasExpr(item); ok {
+			return "", emptyBinds, fmt.Errorf("expression '%s' of the data number %d is not supported", field, index+1)
+		}
+		placeholders := []string{}
+		for _, key := range columns {
+			value, ok := item[key]
+			if !ok {
+				return "", emptyBinds, fmt.Errorf("key '%s' not found in the data number %d", key, index+1)
+			}
+			bindKey := fmt.Sprintf("%s_%d", key, index)
+			placeholders = append(placeholders, fmt.Sprintf(":%s", bindKey))
+			binds[bindKey] = value
+		}
+		rows = append(rows, fmt.Sprintf("(%s)", strings.Join(placeholders, ", ")))
+	}
+
+	fields := []string{}
+	for _, key := range columns {
+		if containsString(keyEdits, key) {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s = VALUES(%s)", dialect.quote(key), dialect.quote(key)))
+	}
+	if len(fields) == 0 {
+		return "", emptyBinds, errors.New("no field to update")
+	}
+
+	query = fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE %s",
+		dialect.quote(table),
+		strings.Join(dialect.quoteAll(columns), ", "),
+		strings.Join(rows, ", "),
+		strings.Join(fields, ", "),
+	)
+	return query, binds, nil
+}
+
+// SelectKeysQuery is used to build query that selects rows which keys exist in data
+//
+// e.g. SELECT `name` FROM `user` WHERE (`code`, `id`) IN ((:code_0, :id_0), (:code_1, :id_1))
+//
+// forUpdate is used to lock selected rows until the end of transaction
+func SelectKeysQuery(dialect Dialect, table string, fields []string, data []map[string]any, keyEdits []string, forUpdate bool) (query string, binds map[string]any, err error) {
+	if len(fields) == 0 {
+		return "", map[string]any{}, errors.New("fields is empty")
+	}
+	if err := validateIdentifiers(table, fields...); err != nil {
+		return "", map[string]any{}, err
+	}
+	return keysQuery(dialect, table, strings.Join(dialect.quoteAll(fields), ", "), data, keyEdits, forUpdate)
+}
+
+// CountKeysQuery is the same as SelectKeysQuery, but only selects the number of rows found
+//
+// e.g. SELECT COUNT(*) FROM `user` WHERE `id` IN (:id_0, :id_1)
+func CountKeysQuery(dialect Dialect, table string, data []map[string]any, keyEdits []string, forUpdate bool) (query string, binds map[string]any, err error) {
+	return keysQuery(dialect, table, "COUNT(*)", data, keyEdits, forUpdate)
+}
+
+func keysQuery(dialect Dialect, table, selected string, data []map[string]any, keyEdits []string, forUpdate bool) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	condition, binds, err := keysCondition(dialect, table, data, keyEdits)
+	if err != nil {
+		return "", map[string]any{}, err
+	}
+
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", selected, dialect.quote(table), condition)
+	if forUpdate {
+		query = fmt.Sprintf("%s FOR UPDATE", query)
+	}
+	return query, binds, nil
+}
+
+// DeleteKeysQuery is used to build query that deletes rows which keys exist in data
+//
+// e.g. DELETE FROM `user` WHERE (`code`, `id`) IN ((:code_0, :id_0), (:code_1, :id_1))
+func DeleteKeysQuery(dialect Dialect, table string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	condition, binds, err := keysCondition(dialect, table, data, keyEdits)
+	if err != nil {
+		return "", map[string]any{}, err
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s", dialect.quote(table), condition), binds, nil
+}
+
+// SoftDeleteKeysQuery is used to build query that marks rows which keys exist in data as deleted,
+// rows that are already deleted keep their deleted time
+//
+// e.g. UPDATE `user` SET `deleted_at` = CURRENT_TIMESTAMP WHERE `id` IN (:id_0, :id_1) AND `deleted_at` IS NULL
+func SoftDeleteKeysQuery(dialect Dialect, table, column string, data []map[string]any, keyEdits []string) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	if err := validateIdentifiers(table, column); err != nil {
+		return "", map[string]any{}, err
+	}
+	condition, binds, err := keysCondition(dialect, table, data, keyEdits)
+	if err != nil {
+		return "", map[string]any{}, err
+	}
+	quoted := dialect.quote(column)
+	query = fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP WHERE %s AND %s IS NULL", dialect.quote(table), quoted, condition, quoted)
+	return query, binds, nil
+}
+
+// keysCondition is used to build condition that matches keys of every data, e.g. `id` IN (:id_0, :id_1)
+func keysCondition(dialect Dialect, table string, data []map[string]any, keyEdits []string) (condition string, binds map[string]any, err error) {
+	emptyBinds := map[string]any{}
+	if len(data) == 0 {
+		return "", emptyBinds, errors.New("data is empty")
+	}
+	if len(keyEdits) == 0 {
+		return "", emptyBinds, errors.New("key edit is empty")
+	}
+	sort.Strings(keyEdits)
+	if err := validateIdentifiers(table, keyEdits...); err != nil {
+		return "", emptyBinds, err
+	}
+
+	binds = map[string]any{}
+	tuples := []string{}
+	for index, item := range data {
+		placeholders := []string{}
+		for _, key := range keyEdits {
+			value, ok := item[key]
+			if !ok {
+				return "", emptyBinds, fmt.Errorf("key '%s' not found in the data number %d", key, index+1)
+			}
+			bindKey := fmt.Sprintf("%s_%d", key, index)
+			placeholders = append(placeholders, fmt.Sprintf(":%s", bindKey))
+			binds[bindKey] = value
+		}
+		tuple := strings.Join(placeholders, ", ")
+		if len(keyEdits) > 1 {
+			tuple = fmt.Sprintf("(%s)", tuple)
+		}
+		tuples = append(tuples, tuple)
+	}
+
+	keys := strings.Join(dialect.quoteAll(keyEdits), ", ")
+	if len(keyEdits) > 1 {
+		keys = fmt.Sprintf("(%s)", keys)
+	}
+	return fmt.Sprintf("%s IN (%s)", keys, strings.Join(tuples, ", ")), binds, nil
+}
+
+// CreateTemporaryTableQuery is used to build query that creates empty temporary table
+// with the same column types as the selected columns of the table
+//
+// the temporary table has no key, use AddPrimaryKeyQuery so it can be joined efficiently
+func CreateTemporaryTableQuery(dialect Dialect, tmpTable, table string, columns []string) (query string, err error) {
+	if tmpTable == "" || table == "" {
+		return "", errors.New("table is empty")
+	}
+	if len(columns) == 0 {
+		return "", errors.New("columns is empty")
+	}
+	if err := validateIdentifiers(tmpTable, columns...); err != nil {
+		return "", err
+	}
+	if err := ValidateTable(table); err != nil {
+		return "", err
+	}
+	query = fmt.Sprintf(
+		"CREATE TEMPORARY TABLE %s AS SELECT %s FROM %s LIMIT 0",
+		dialect.quote(tmpTable),
+		strings.Join(dialect.quoteAll(columns), ", "),
+		dialect.quote(table),
+	)
+	return query, nil
+}
+
+// AddPrimaryKeyQuery is used to build query that adds keyEdits as primary key of the table
+//
+// e.g. ALTER TABLE `tmp_user` ADD PRIMARY KEY (`id`)
+func AddPrimaryKeyQuery(dialect Dialect, table string, keyEdits []string) (query string, err error) {
+	if table == "" {
+		return "", errors.New("table is empty")
+	}
+	if len(keyEdits) == 0 {
+		return "", errors.New("key edit is empty")
+	}
+	if err := validateIdentifiers(table, keyEdits...); err != nil {
+		return "", err
+	}
+	query = fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s)", dialect.quote(table), strings.Join(dialect.quoteAll(keyEdits), ", "))
+	return query, nil
+}
+
+// UpdateJoinQuery is used to build query that updates table using values from other table
+// that has the same keyEdits, e.g. temporary table
+//
+// columns are fields that will be updated, keyEdits should not be included
+func UpdateJoinQuery(dialect Dialect, table, sourceTable string, columns, keyEdits []string) (query string, err error) {
+	if table == "" || sourceTable == "" {
+		return "", errors.New("table is empty")
+	}
+	if len(columns) == 0 {
+		return "", errors.New("columns is empty")
+	}
+	if len(keyEdits) == 0 {
+		return "", errors.New("key edit is empty")
+	}
+	if err := validateIdentifiers(table, columns...); err != nil {
+		return "", err
+	}
+	if err := validateIdentifiers(sourceTable, keyEdits...); err != nil {
+		return "", err
+	}
+	fields := []string{}
+	for _, column := range columns {
+		fields = append(fields, fmt.Sprintf("%s.%s = %s.%s", dialect.quote(table), dialect.quote(column), dialect.quote(sourceTable), dialect.quote(column)))
+	}
+	query = fmt.Sprintf(
+		"UPDATE %s JOIN %s USING (%s) SET %s",
+		dialect.quote(table),
+		dialect.quote(sourceTable),
+		strings.Join(dialect.quoteAll(keyEdits), ", "),
+		strings.Join(fields, ", "),
+	)
+	return query, nil
+}
+
 // CreateQuery is used to build create query
-func CreateQuery(table string, data map[string]any) (query string, binds map[string]any, err error) {
+//
+// query only has fields of data, so data with different fields need to be grouped first, see GroupByFields
+func CreateQuery(dialect Dialect, table string, data map[string]any) (query string, binds map[string]any, err error) {
 	emptyBinds := map[string]any{}
 	if table == "" {
 		return "", emptyBinds, errors.New("table is empty")
 	}
+	data = ResolveOptional(data)
 	if len(data) == 0 {
 		return "", emptyBinds, errors.New("data is empty")
 	}
+	if err := ValidateTable(table); err != nil {
+		return "", emptyBinds, err
+	}
+	if err := validateFields(data); err != nil {
+		return "", emptyBinds, err
+	}
 
 	binds = map[string]any{}
 	fields := []string{}
@@ -117,13 +514,13 @@ func CreateQuery(table string, data map[string]any) (query string, binds map[str
 
 	for _, key := range SortMapKeys(data) {
 		val := data[key]
-		fields = append(fields, key)
+		fields = append(fields, dialect.quote(key))
 		placeholders = append(placeholders, fmt.Sprintf(":%s", key))
 		binds[key] = val
 	}
 	query = fmt.Sprintf(
 		"INSERT INTO %s (%s) VALUES (%s)",
-		table,
+		dialect.quote(table),
 		strings.Join(fields, ", "),
 		strings.Join(placeholders, ", "),
 	)
@@ -131,17 +528,28 @@ func CreateQuery(table string, data map[string]any) (query string, binds map[str
 }
 
 // UpdateQuery to build update data query
-func UpdateQuery(table string, payload, condition map[string]any) (query string, binds map[string]any, err error) {
+//
+// unset Optional field of payload is not updated, null Optional field is updated to NULL
+//
+// Expr value is inlined, its binds are renamed using field e.g. :val_stock_n
+func UpdateQuery(dialect Dialect, table string, payload, condition map[string]any) (query string, binds map[string]any, err error) {
 	empty := map[string]any{}
 	if table == "" {
 		return "", empty, errors.New("table is empty")
 	}
+	payload = ResolveOptional(payload)
 	if len(payload) == 0 {
 		return "", empty, errors.New("payload is empty")
 	}
 	if len(condition) == 0 {
 		return "", empty, errors.New("condition is empty")
 	}
+	if err := ValidateTable(table); err != nil {
+		return "", empty, err
+	}
+	if err := validateFields(payload); err != nil {
+		return "", empty, err
+	}
 
 	// Field
 	binds = map[string]any{}
@@ -149,12 +557,23 @@ func UpdateQuery(table string, payload, condition map[string]any) (query string,
 	for _, key := range SortMapKeys(payload) {
 		keyBind := fmt.Sprintf("val_%s", key)
 		val := payload[key]
-		fields = append(fields, fmt.Sprintf("%s = :%s", key, keyBind))
+		if expr, ok := val.(Expr); ok {
+			exprQuery, exprBinds, err := expr.inline(keyBind)
+			if err != nil {
+				return "", empty, fmt.Errorf("failed build expression '%s': %w", key, err)
+			}
+			fields = append(fields, fmt.Sprintf("%s = %s", dialect.quote(key), exprQuery))
+			for k, v := range exprBinds {
+				binds[k] = v
+			}
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("%s = :%s", dialect.quote(key), keyBind))
 		binds[keyBind] = val
 	}
 
 	// Condition
-	conditionQuery, conditionBind, err := ConditionQuery(condition)
+	conditionQuery, conditionBind, err := ConditionQuery(dialect, condition)
 	if err != nil {
 		return query, binds, fmt.Errorf("failed to build update query, make condition: %w", err)
 	}
@@ -163,27 +582,30 @@ func UpdateQuery(table string, payload, condition map[string]any) (query string,
 	}
 
 	// Query
-	query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(fields, ", "), conditionQuery)
+	query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", dialect.quote(table), strings.Join(fields, ", "), conditionQuery)
 	return query, binds, nil
 }
 
-func DeleteQuery(table string, condition map[string]any) (query string, bind map[string]any, err error) {
+func DeleteQuery(dialect Dialect, table string, condition map[string]any) (query string, bind map[string]any, err error) {
 	if table == "" {
 		return "", map[string]any{}, fmt.Errorf("table is empty")
 	}
 	if len(condition) == 0 {
 		return "", map[string]any{}, fmt.Errorf("condition is empty")
 	}
+	if err := ValidateTable(table); err != nil {
+		return "", map[string]any{}, err
+	}
 
-	conditionQuery, conditionBind, err := ConditionQuery(condition)
+	conditionQuery, conditionBind, err := ConditionQuery(dialect, condition)
 	if err != nil {
 		return "", map[string]any{}, fmt.Errorf("failed build condition: %w", err)
 	}
-	query = fmt.Sprintf("DELETE FROM %s WHERE %s", table, conditionQuery)
+	query = fmt.Sprintf("DELETE FROM %s WHERE %s", dialect.quote(table), conditionQuery)
 	return query, conditionBind, nil
 }
 
-func SelectQuery(table string, fields []string, condition *map[string]any, paginate *Paginate) (query string, bind map[string]any, err error) {
+func SelectQuery(dialect Dialect, table string, fields []string, condition *map[string]any, paginate *Paginate) (query string, bind map[string]any, err error) {
 	bind = map[string]any{}
 	if table == "" {
 		return "", map[string]any{}, errors.New("table is empty")
@@ -195,12 +617,28 @@ func SelectQuery(table string, fields []string, condition *map[string]any, pagin
 		return "", map[string]any{}, errors.New("condition provided but empty")
 	}
 
-	sort.Strings(fields)
-	query = fmt.Sprintf("SELECT %s FROM %s", strings.Join(fields, ", "), table)
+	// wildcard is the only field that is not an identifier
+	selected := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field == "*" {
+			selected = append(selected, field)
+			continue
+		}
+		if err := ValidateIdentifier(field); err != nil {
+			return "", map[string]any{}, err
+		}
+		selected = append(selected, dialect.quote(field))
+	}
+	if err := ValidateTable(table); err != nil {
+		return "", map[string]any{}, err
+	}
+
+	sort.Strings(selected)
+	query = fmt.Sprintf("SELECT %s FROM %s", strings.Join(selected, ", "), dialect.quote(table))
 
 	// Condition
 	if condition != nil {
-		conditionQuery, conditionBind, err := ConditionQuery(*condition)
+		conditionQuery, conditionBind, err := ConditionQuery(dialect, *condition)
 		if err != nil {
 			return "", map[string]any{}, fmt.Errorf("failed build condition: %w", err)
 		}
@@ -220,28 +658,57 @@ func SelectQuery(table string, fields []string, condition *map[string]any, pagin
 	return query, bind, nil
 }
 
-// ConditionQuery is used to build conditional query in mysql
+// ConditionQuery is used to build conditional query
+//
+// e.g. WHERE `id` = :cond_id AND `name` = :cond_name
 //
-// e.g. WHERE id=:cond_id AND name=:cond_name
-func ConditionQuery(condition map[string]any) (query string, binds map[string]any, err error) {
+// nil value is compared using IS NULL, e.g. `deleted_at` IS NULL
+func ConditionQuery(dialect Dialect, condition map[string]any) (query string, binds map[string]any, err error) {
+	return conditionQuery(dialect, "", "cond", condition)
+}
+
+// GuardQuery is used to build extra condition of update query, e.g. `user`.`status` = :guard_status
+//
+// column is qualified using table, so it can be appended to update that joins other table
+func GuardQuery(dialect Dialect, table string, guard map[string]any) (query string, binds map[string]any, err error) {
+	if table == "" {
+		return "", map[string]any{}, errors.New("table is empty")
+	}
+	if err := ValidateTable(table); err != nil {
+		return "", map[string]any{}, err
+	}
+	return conditionQuery(dialect, dialect.quote(table)+".", "guard", guard)
+}
+
+// conditionQuery is used to build conditions joined by AND, column is prefixed by qualifier and bind by prefix
+func conditionQuery(dialect Dialect, qualifier, prefix string, condition map[string]any) (query string, binds map[string]any, err error) {
 	if len(condition) == 0 {
 		return "", map[string]any{}, errors.New("condition is empty")
 	}
+	if err := validateFields(condition); err != nil {
+		return "", map[string]any{}, err
+	}
 
+	condition = ResolveOptional(condition)
 	binds = map[string]any{}
 	cond := []string{}
 	for _, key := range SortMapKeys(condition) {
 		val := condition[key]
+		column := qualifier + dialect.quote(key)
+		bindKey := fmt.Sprintf("%s_%s", prefix, key)
+		if resolved, err := resolveValue(val); err == nil && resolved == nil {
+			cond = append(cond, fmt.Sprintf("%s IS NULL", column))
+			continue
+		}
 		kind := reflect.TypeOf(val).Kind()
-		bindKey := fmt.Sprintf("cond_%s", key)
 		str := ""
 		if kind == reflect.Array || kind == reflect.Slice {
 			if reflect.ValueOf(val).Len() == 0 {
 				continue
 			}
-			str = fmt.Sprintf("%s IN (:%s)", key, bindKey)
+			str = fmt.Sprintf("%s IN (:%s)", column, bindKey)
 		} else {
-			str = fmt.Sprintf("%s = :%s", key, bindKey)
+			str = fmt.Sprintf("%s = :%s", column, bindKey)
 		}
 		cond = append(cond, str)
 		binds[bindKey] = val
@@ -266,3 +733,12 @@ func UglifyQuery(query string) string {
 	query = strings.TrimSpace(query)
 	return query
 }
+
+func containsString(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBulkMaxDataSize(t *testing.T) {
@@ -65,61 +67,118 @@ func TestBulkUpdateQuery(t *testing.T) {
 				},
 				query: `
 					UPDATE
-						user
+						"user"
 					SET
-						address = (
+						"address" = (
 							CASE
-								WHEN id = :id_1 THEN :address_1
-								ELSE address
+								WHEN "id" = :id_1 THEN :address_1
+								ELSE "address"
 							END
 						),
-						age = (
+						"age" = (
 							CASE
-								WHEN id = :id_0 THEN :age_0
-								WHEN id = :id_1 THEN :age_1
-								ELSE age
+								WHEN "id" = :id_0 THEN :age_0
+								WHEN "id" = :id_1 THEN :age_1
+								ELSE "age"
 							END
 						),
-						name = (
+						"name" = (
 							CASE
-								WHEN id = :id_0 THEN :name_0
-								WHEN id = :id_1 THEN :name_1
-								ELSE name
+								WHEN "id" = :id_0 THEN :name_0
+								WHEN "id" = :id_1 THEN :name_1
+								ELSE "name"
 							END
 						)
 					WHERE
-						id IN (:id_0, :id_1)
+						"id" IN (:id_0, :id_1)
 				`,
 				binds: map[string]any{
 					"id_0": 1, "age_0": 1, "name_0": "Name0",
 					"id_1": 2, "age_1": 2, "name_1": "Name1", "address_1": "Addr1",
 				},
 			},
+			{
+				table:   "user",
+				keyEdit: []string{"id"},
+				data: []map[string]any{
+					{"id": 1, "name": Null[string](), "age": Optional[int]{}},
+					{"id": 2, "name": Optional[string]{}, "age": Set(2)},
+				},
+				query: `
+					UPDATE
+						"user"
+					SET
+						"age" = (
+							CASE
+								WHEN "id" = :id_1 THEN :age_1
+								ELSE "age"
+							END
+						),
+						"name" = (
+							CASE
+								WHEN "id" = :id_0 THEN :name_0
+								ELSE "name"
+							END
+						)
+					WHERE
+						"id" IN (:id_0, :id_1)
+				`,
+				binds: map[string]any{"id_0": 1, "name_0": nil, "id_1": 2, "age_1": 2},
+			},
+			{
+				table:   "product",
+				keyEdit: []string{"id"},
+				data: []map[string]any{
+					{"id": 1, "stock": NewExpr("stock - :n", map[string]any{"n": 2}), "updated_at": NewExpr("NOW()", nil)},
+					{"id": 2, "stock": NewExpr("stock - :n", map[string]any{"n": 5})},
+				},
+				query: `
+					UPDATE
+						"product"
+					SET
+						"stock" = (
+							CASE
+								WHEN "id" = :id_0 THEN stock - :stock_0_n
+								WHEN "id" = :id_1 THEN stock - :stock_1_n
+								ELSE "stock"
+							END
+						),
+						"updated_at" = (
+							CASE
+								WHEN "id" = :id_0 THEN NOW()
+								ELSE "updated_at"
+							END
+						)
+					WHERE
+						"id" IN (:id_0, :id_1)
+				`,
+				binds: map[string]any{"id_0": 1, "stock_0_n": 2, "id_1": 2, "stock_1_n": 5},
+			},
 			{
 				table:   "user",
 				keyEdit: []string{"id", "name"},
 				data:    []map[string]any{{"id": 1, "name": "Name0", "age": 1, "address": "Addr1"}},
 				query: `
 					UPDATE
-						user
+						"user"
 					SET
-						address = (
+						"address" = (
 							CASE
-								WHEN id = :id_0
-								AND name = :name_0 THEN :address_0
-								ELSE address
+								WHEN "id" = :id_0
+								AND "name" = :name_0 THEN :address_0
+								ELSE "address"
 							END
 						),
-						age = (
+						"age" = (
 							CASE
-								WHEN id = :id_0
-								AND name = :name_0 THEN :age_0
-								ELSE age
+								WHEN "id" = :id_0
+								AND "name" = :name_0 THEN :age_0
+								ELSE "age"
 							END
 						)
 					WHERE
-						id IN (:id_0)
-						AND name IN (:name_0)
+						"id" IN (:id_0)
+						AND "name" IN (:name_0)
 				`,
 				binds: map[string]any{
 					"id_0": 1, "age_0": 1, "name_0": "Name0", "address_0": "Addr1",
@@ -129,7 +188,7 @@ func TestBulkUpdateQuery(t *testing.T) {
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, binds, err := BulkUpdateQuery(testCase.table, testCase.data, testCase.keyEdit)
+				query, binds, err := BulkUpdateQuery(Postgres, testCase.table, testCase.data, testCase.keyEdit)
 				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
 				assert.Equal(t, testCase.binds, binds)
 				assert.Nil(t, err)
@@ -164,10 +223,15 @@ func TestBulkUpdateQuery(t *testing.T) {
 				keyEdit: []string{"non_exists"},
 				data:    []map[string]any{{"id": 1, "name": "Name0", "age": 1}},
 			},
+			{
+				table:   "table",
+				keyEdit: []string{"id"},
+				data:    []map[string]any{{"id": 1}, {"id": 2}},
+			},
 		}
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				_, _, err := BulkUpdateQuery(testCase.table, testCase.data, testCase.keyEdit)
+				_, _, err := BulkUpdateQuery(Postgres, testCase.table, testCase.data, testCase.keyEdit)
 				assert.NotNil(t, err)
 			})
 		}
@@ -189,20 +253,20 @@ func TestCreateQuery(t *testing.T) {
 			{
 				table: "user",
 				data:  map[string]any{"id": 1, "name": "John", "address": "Australia"},
-				query: "INSERT INTO user (address, id, name) VALUES (:address, :id, :name)",
+				query: "INSERT INTO `user` (`address`, `id`, `name`) VALUES (:address, :id, :name)",
 				bind:  map[string]any{"id": 1, "name": "John", "address": "Australia"},
 			},
 			{
 				table: "product",
 				data:  map[string]any{"id": 1, "name": "Mouse", "qty": 2},
-				query: "INSERT INTO product (id, name, qty) VALUES (:id, :name, :qty)",
+				query: "INSERT INTO `product` (`id`, `name`, `qty`) VALUES (:id, :name, :qty)",
 				bind:  map[string]any{"id": 1, "name": "Mouse", "qty": 2},
 			},
 		}
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, bind, err := CreateQuery(testCase.table, testCase.data)
+				query, bind, err := CreateQuery(MySQL, testCase.table, testCase.data)
 				assert.Nil(t, err)
 				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
 				assert.Equal(t, testCase.bind, bind)
@@ -211,10 +275,10 @@ func TestCreateQuery(t *testing.T) {
 	})
 
 	t.Run("fail", func(t *testing.T) {
-		_, _, err := CreateQuery("", map[string]any{"id": 1})
+		_, _, err := CreateQuery(MySQL, "", map[string]any{"id": 1})
 		assert.NotNil(t, err)
 
-		_, _, err = CreateQuery("table", map[string]any{})
+		_, _, err = CreateQuery(MySQL, "table", map[string]any{})
 		assert.NotNil(t, err)
 	})
 }
@@ -234,21 +298,35 @@ func TestUpdateQuery(t *testing.T) {
 				table:     "table",
 				field:     map[string]any{"f1": "1", "f2": "2"},
 				condition: map[string]any{"c1": 1, "c2": 2},
-				query:     "UPDATE table SET f1 = :val_f1, f2 = :val_f2 WHERE c1 = :cond_c1 AND c2 = :cond_c2",
+				query:     "UPDATE `table` SET `f1` = :val_f1, `f2` = :val_f2 WHERE `c1` = :cond_c1 AND `c2` = :cond_c2",
 				bind:      map[string]any{"val_f1": "1", "val_f2": "2", "cond_c1": 1, "cond_c2": 2},
 			},
 			{
 				table:     "table",
 				field:     map[string]any{"f1": "1", "f2": "2"},
 				condition: map[string]any{"f1": 1, "f2": 2},
-				query:     "UPDATE table SET f1 = :val_f1, f2 = :val_f2 WHERE f1 = :cond_f1 AND f2 = :cond_f2",
+				query:     "UPDATE `table` SET `f1` = :val_f1, `f2` = :val_f2 WHERE `f1` = :cond_f1 AND `f2` = :cond_f2",
 				bind:      map[string]any{"val_f1": "1", "val_f2": "2", "cond_f1": 1, "cond_f2": 2},
 			},
+			{
+				table:     "table",
+				field:     map[string]any{"f1": Null[string](), "f2": Optional[string]{}, "f3": Set("3")},
+				condition: map[string]any{"c1": 1},
+				query:     "UPDATE `table` SET `f1` = :val_f1, `f3` = :val_f3 WHERE `c1` = :cond_c1",
+				bind:      map[string]any{"val_f1": nil, "val_f3": "3", "cond_c1": 1},
+			},
+			{
+				table:     "product",
+				field:     map[string]any{"stock": NewExpr("stock + :n * :m", map[string]any{"n": 2, "m": 3}), "price": NewExpr("price::numeric", nil)},
+				condition: map[string]any{"id": 1},
+				query:     "UPDATE `product` SET `price` = price::::numeric, `stock` = stock + :val_stock_n * :val_stock_m WHERE `id` = :cond_id",
+				bind:      map[string]any{"val_stock_n": 2, "val_stock_m": 3, "cond_id": 1},
+			},
 		}
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, bind, err := UpdateQuery(testCase.table, testCase.field, testCase.condition)
+				query, bind, err := UpdateQuery(MySQL, testCase.table, testCase.field, testCase.condition)
 				assert.Nil(t, err)
 				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
 				assert.Equal(t, testCase.bind, bind)
@@ -257,16 +335,16 @@ func TestUpdateQuery(t *testing.T) {
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		_, _, err := UpdateQuery("", map[string]any{"f1": 1}, map[string]any{"c1": 1})
+		_, _, err := UpdateQuery(MySQL, "", map[string]any{"f1": 1}, map[string]any{"c1": 1})
 		assert.NotNil(t, err)
 
-		_, _, err = UpdateQuery("table", map[string]any{}, map[string]any{"c1": 1})
+		_, _, err = UpdateQuery(MySQL, "table", map[string]any{}, map[string]any{"c1": 1})
 		assert.NotNil(t, err)
 
-		_, _, err = UpdateQuery("table", map[string]any{"f1": 1}, map[string]any{})
+		_, _, err = UpdateQuery(MySQL, "table", map[string]any{"f1": 1}, map[string]any{})
 		assert.NotNil(t, err)
 
-		_, _, err = UpdateQuery("table", map[string]any{"f1": 1}, map[string]any{"c1": []int{}})
+		_, _, err = UpdateQuery(MySQL, "table", map[string]any{"f1": 1}, map[string]any{"c1": []int{}})
 		assert.NotNil(t, err)
 	})
 }
@@ -285,20 +363,20 @@ func TestDeleteQuery(t *testing.T) {
 			{
 				table:     "table",
 				condition: map[string]any{"id": 1},
-				query:     "DELETE FROM table WHERE id = :cond_id",
+				query:     "DELETE FROM `table` WHERE `id` = :cond_id",
 				bind:      map[string]any{"cond_id": 1},
 			},
 			{
 				table:     "user",
 				condition: map[string]any{"address": "Denpasar", "nationality": "Indonesia"},
-				query:     "DELETE FROM user WHERE address = :cond_address AND nationality = :cond_nationality",
+				query:     "DELETE FROM `user` WHERE `address` = :cond_address AND `nationality` = :cond_nationality",
 				bind:      map[string]any{"cond_address": "Denpasar", "cond_nationality": "Indonesia"},
 			},
 		}
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, bind, err := DeleteQuery(testCase.table, testCase.condition)
+				query, bind, err := DeleteQuery(MySQL, testCase.table, testCase.condition)
 				assert.Nil(t, err)
 				assert.Equal(t, testCase.query, query)
 				assert.Equal(t, testCase.bind, bind)
@@ -307,13 +385,13 @@ func TestDeleteQuery(t *testing.T) {
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		_, _, err := DeleteQuery("", map[string]any{"field": 1})
+		_, _, err := DeleteQuery(MySQL, "", map[string]any{"field": 1})
 		assert.NotNil(t, err)
 
-		_, _, err = DeleteQuery("table", map[string]any{})
+		_, _, err = DeleteQuery(MySQL, "table", map[string]any{})
 		assert.NotNil(t, err)
 
-		_, _, err = DeleteQuery("table", map[string]any{"ids": []int{}})
+		_, _, err = DeleteQuery(MySQL, "table", map[string]any{"ids": []int{}})
 		assert.NotNil(t, err)
 	})
 
@@ -322,6 +400,7 @@ func TestDeleteQuery(t *testing.T) {
 func TestSelectQuery(t *testing.T) {
 
 	type testCase struct {
+		dialect   Dialect
 		table     string
 		fields    []string
 		condition *map[string]any
@@ -335,29 +414,30 @@ func TestSelectQuery(t *testing.T) {
 			{
 				table:  "table",
 				fields: []string{"field1", "field2"},
-				query:  "SELECT field1, field2 FROM table",
+				query:  "SELECT `field1`, `field2` FROM `table`",
 				bind:   map[string]any{},
 			},
 			{
 				table:     "table",
 				fields:    []string{"field1", "field2"},
 				condition: &map[string]any{"field3": 1},
-				query:     "SELECT field1, field2 FROM table WHERE field3 = :cond_field3",
+				query:     "SELECT `field1`, `field2` FROM `table` WHERE `field3` = :cond_field3",
 				bind:      map[string]any{"cond_field3": 1},
 			},
 			{
+				dialect:   Postgres,
 				table:     "table",
 				fields:    []string{"field1", "field2"},
 				condition: &map[string]any{"field3": 1},
 				paginate:  &Paginate{Page: 1, Limit: 10},
 				query: `
 					SELECT
-						field1,
-						field2
+						"field1",
+						"field2"
 					FROM
-						table
+						"table"
 					WHERE
-						field3 = :cond_field3
+						"field3" = :cond_field3
 					LIMIT
 						:paginate_limit OFFSET :paginate_offset
 				`,
@@ -367,7 +447,7 @@ func TestSelectQuery(t *testing.T) {
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, bind, err := SelectQuery(testCase.table, testCase.fields, testCase.condition, testCase.paginate)
+				query, bind, err := SelectQuery(testCase.dialect, testCase.table, testCase.fields, testCase.condition, testCase.paginate)
 				assert.Nil(t, err)
 				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
 				assert.Equal(t, testCase.bind, bind)
@@ -376,16 +456,16 @@ func TestSelectQuery(t *testing.T) {
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		_, _, err := SelectQuery("", []string{"f1", "f2"}, nil, nil)
+		_, _, err := SelectQuery(MySQL, "", []string{"f1", "f2"}, nil, nil)
 		assert.NotNil(t, err)
 
-		_, _, err = SelectQuery("table", []string{}, nil, nil)
+		_, _, err = SelectQuery(MySQL, "table", []string{}, nil, nil)
 		assert.NotNil(t, err)
 
-		_, _, err = SelectQuery("table", []string{"f1", "f2"}, &map[string]any{}, nil)
+		_, _, err = SelectQuery(MySQL, "table", []string{"f1", "f2"}, &map[string]any{}, nil)
 		assert.NotNil(t, err)
 
-		_, _, err = SelectQuery("table", []string{"f1", "f2"}, &map[string]any{"ids": []int{}}, nil)
+		_, _, err = SelectQuery(MySQL, "table", []string{"f1", "f2"}, &map[string]any{"ids": []int{}}, nil)
 		assert.NotNil(t, err)
 	})
 }
@@ -402,19 +482,24 @@ func TestConditionQuery(t *testing.T) {
 		testCases := []testCase{
 			{
 				condition: map[string]any{"c1": 1, "c2": 2},
-				query:     "c1 = :cond_c1 AND c2 = :cond_c2",
+				query:     "`c1` = :cond_c1 AND `c2` = :cond_c2",
 				bind:      map[string]any{"cond_c1": 1, "cond_c2": 2},
 			},
 			{
 				condition: map[string]any{"c1": 1, "c2": 2, "c3": []int{1, 2}, "c4": []int{}},
-				query:     "c1 = :cond_c1 AND c2 = :cond_c2 AND c3 IN (:cond_c3)",
+				query:     "`c1` = :cond_c1 AND `c2` = :cond_c2 AND `c3` IN (:cond_c3)",
 				bind:      map[string]any{"cond_c1": 1, "cond_c2": 2, "cond_c3": []int{1, 2}},
 			},
+			{
+				condition: map[string]any{"c1": 1, "deleted_at": nil, "reason": Null[string](), "note": Optional[string]{}},
+				query:     "`c1` = :cond_c1 AND `deleted_at` IS NULL AND `reason` IS NULL",
+				bind:      map[string]any{"cond_c1": 1},
+			},
 		}
 
 		for index, testCase := range testCases {
 			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
-				query, bind, err := ConditionQuery(testCase.condition)
+				query, bind, err := ConditionQuery(MySQL, testCase.condition)
 				assert.Nil(t, err)
 				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
 				assert.Equal(t, testCase.bind, bind)
@@ -423,10 +508,374 @@ func TestConditionQuery(t *testing.T) {
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		_, _, err := ConditionQuery(map[string]any{})
+		_, _, err := ConditionQuery(MySQL, map[string]any{})
+		assert.NotNil(t, err)
+
+		_, _, err = ConditionQuery(MySQL, map[string]any{"ids": []int{}})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGuardQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query, binds, err := GuardQuery(MySQL, "db.user", map[string]any{"status": "pending", "deleted_at": nil})
+		assert.Nil(t, err)
+		assert.Equal(t, "`db`.`user`.`deleted_at` IS NULL AND `db`.`user`.`status` = :guard_status", query)
+		assert.Equal(t, map[string]any{"guard_status": "pending"}, binds)
+
+		query, _, err = GuardQuery(Postgres, "user", map[string]any{"status": []string{"a", "b"}})
+		assert.Nil(t, err)
+		assert.Equal(t, `"user"."status" IN (:guard_status)`, query)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, _, err := GuardQuery(MySQL, "", map[string]any{"status": "pending"})
+		assert.NotNil(t, err)
+
+		_, _, err = GuardQuery(MySQL, "user", map[string]any{})
+		assert.NotNil(t, err)
+
+		_, _, err = GuardQuery(MySQL, "user", map[string]any{"status = 'done' OR 1": 1})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCreateTemporaryTableQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query, err := CreateTemporaryTableQuery(MySQL, "tmp_user", "user", []string{"id", "name", "age"})
+		assert.Nil(t, err)
+		assert.Equal(t, "CREATE TEMPORARY TABLE `tmp_user` AS SELECT `id`, `name`, `age` FROM `user` LIMIT 0", query)
+
+		query, err = AddPrimaryKeyQuery(MySQL, "tmp_user", []string{"id", "code"})
+		assert.Nil(t, err)
+		assert.Equal(t, "ALTER TABLE `tmp_user` ADD PRIMARY KEY (`id`, `code`)", query)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, err := CreateTemporaryTableQuery(MySQL, "", "user", []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = CreateTemporaryTableQuery(MySQL, "tmp_user", "", []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = CreateTemporaryTableQuery(MySQL, "tmp_user", "user", []string{})
+		assert.NotNil(t, err)
+
+		_, err = AddPrimaryKeyQuery(MySQL, "tmp_user", []string{})
+		assert.NotNil(t, err)
+
+		_, err = AddPrimaryKeyQuery(MySQL, "", []string{"id"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdateJoinQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		query, err := UpdateJoinQuery(MySQL, "user", "tmp_user", []string{"age", "name"}, []string{"id", "code"})
+		assert.Nil(t, err)
+		assert.Equal(t, "UPDATE `user` JOIN `tmp_user` USING (`id`, `code`) SET `user`.`age` = `tmp_user`.`age`, `user`.`name` = `tmp_user`.`name`", query)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, err := UpdateJoinQuery(MySQL, "", "tmp_user", []string{"name"}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = UpdateJoinQuery(MySQL, "user", "", []string{"name"}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = UpdateJoinQuery(MySQL, "user", "tmp_user", []string{}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, err = UpdateJoinQuery(MySQL, "user", "tmp_user", []string{"name"}, []string{})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestBulkUpdateValuesEstimateTotalField(t *testing.T) {
+	assert.Equal(t, 40, BulkUpdateValuesEstimateTotalField(10, 4))
+	assert.Equal(t, 8, BulkUpdateValuesEstimateTotalField(2, 4))
+}
+
+func TestBulkUpdateValuesQuery(t *testing.T) {
+
+	type testCase struct {
+		table   string
+		keyEdit []string
+		data    []map[string]any
+		query   string
+		binds   map[string]any
+	}
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{
+				table:   "user",
+				keyEdit: []string{"id"},
+				data: []map[string]any{
+					{"id": 1, "name": "Name0", "age": 1},
+					{"id": 2, "name": "Name1", "age": 2},
+				},
+				query: "UPDATE `user` JOIN ( VALUES ROW(:age_0, :id_0, :name_0), ROW(:age_1, :id_1, :name_1) ) AS new_values (`age`, `id`, `name`) ON `user`.`id` = new_values.`id` SET `user`.`age` = new_values.`age`, `user`.`name` = new_values.`name`",
+				binds: map[string]any{
+					"id_0": 1, "age_0": 1, "name_0": "Name0",
+					"id_1": 2, "age_1": 2, "name_1": "Name1",
+				},
+			},
+			{
+				table:   "user",
+				keyEdit: []string{"name", "id"},
+				data:    []map[string]any{{"id": 1, "name": "Name0", "age": 1}},
+				query:   "UPDATE `user` JOIN ( VALUES ROW(:age_0, :id_0, :name_0) ) AS new_values (`age`, `id`, `name`) ON `user`.`id` = new_values.`id` AND `user`.`name` = new_values.`name` SET `user`.`age` = new_values.`age`",
+				binds:   map[string]any{"id_0": 1, "age_0": 1, "name_0": "Name0"},
+			},
+		}
+
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				query, binds, err := BulkUpdateValuesQuery(MySQL, testCase.table, testCase.data, testCase.keyEdit)
+				assert.Nil(t, err)
+				assert.Equal(t, UglifyQuery(testCase.query), UglifyQuery(query))
+				assert.Equal(t, testCase.binds, binds)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		testCases := []testCase{
+			{table: "", keyEdit: []string{"id"}, data: []map[string]any{{"id": 1, "name": "Name0"}}},
+			{table: "table", keyEdit: []string{}, data: []map[string]any{{"id": 1, "name": "Name0"}}},
+			{table: "table", keyEdit: []string{"id"}, data: []map[string]any{}},
+			{table: "table", keyEdit: []string{"non_exists"}, data: []map[string]any{{"id": 1, "name": "Name0"}}},
+			{table: "table", keyEdit: []string{"id"}, data: []map[string]any{{"id": 1}}},
+			{table: "table", keyEdit: []string{"id"}, data: []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2}}},
+			{table: "table", keyEdit: []string{"id"}, data: []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2, "age": 1}}},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				_, _, err := BulkUpdateValuesQuery(MySQL, testCase.table, testCase.data, testCase.keyEdit)
+				assert.NotNil(t, err)
+			})
+		}
+	})
+}
+
+func TestBulkUpsertQuery(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		data := []map[string]any{
+			{"id": 1, "name": "Name0", "age": 1},
+			{"id": 2, "name": "Name1", "age": 2},
+		}
+		query, binds, err := BulkUpsertQuery(MySQL, "user", data, []string{"id"})
+		assert.Nil(t, err)
+		expected := "INSERT INTO `user` (`age`, `id`, `name`) VALUES (:age_0, :id_0, :name_0), (:age_1, :id_1, :name_1) " +
+			"ON DUPLICATE KEY UPDATE `age` = VALUES(`age`), `name` = VALUES(`name`)"
+		assert.Equal(t, expected, query)
+		assert.Equal(t, map[string]any{
+			"id_0": 1, "age_0": 1, "name_0": "Name0",
+			"id_1": 2, "age_1": 2, "name_1": "Name1",
+		}, binds)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		_, _, err := BulkUpsertQuery(MySQL, "", []map[string]any{{"id": 1, "name": "Name0"}}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, _, err = BulkUpsertQuery(MySQL, "user", []map[string]any{}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, _, err = BulkUpsertQuery(MySQL, "user", []map[string]any{{"id": 1, "name": "Name0"}}, []string{})
+		assert.NotNil(t, err)
+
+		_, _, err = BulkUpsertQuery(MySQL, "user", []map[string]any{{"id": 1, "name": "Name0"}}, []string{"non_exists"})
+		assert.NotNil(t, err)
+
+		_, _, err = BulkUpsertQuery(MySQL, "user", []map[string]any{{"id": 1}}, []string{"id"})
+		assert.NotNil(t, err)
+
+		_, _, err = BulkUpsertQuery(MySQL, "user", []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2, "age": 1}}, []string{"id"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSelectKeysQuery(t *testing.T) {
+	type testCase struct {
+		fields    []string
+		keyEdit   []string
+		data      []map[string]any
+		forUpdate bool
+		query     string
+		binds     map[string]any
+	}
+
+	t.Run("success", func(t *testing.T) {
+		testCases := []testCase{
+			{
+				fields:  []string{"name"},
+				keyEdit: []string{"id"},
+				data:    []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2, "name": "Name1"}},
+				query:   "SELECT `name` FROM `user` WHERE `id` IN (:id_0, :id_1)",
+				binds:   map[string]any{"id_0": 1, "id_1": 2},
+			},
+			{
+				fields:    []string{"id", "name", "age"},
+				keyEdit:   []string{"name", "id"},
+				data:      []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2, "name": "Name1"}},
+				forUpdate: true,
+				query:     "SELECT `id`, `name`, `age` FROM `user` WHERE (`id`, `name`) IN ((:id_0, :name_0), (:id_1, :name_1)) FOR UPDATE",
+				binds:     map[string]any{"id_0": 1, "id_1": 2, "name_0": "Name0", "name_1": "Name1"},
+			},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				query, binds, err := SelectKeysQuery(MySQL, "user", testCase.fields, testCase.data, testCase.keyEdit, testCase.forUpdate)
+				assert.Nil(t, err)
+				assert.Equal(t, testCase.query, query)
+				assert.Equal(t, testCase.binds, binds)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		data := []map[string]any{{"id": 1}}
+		_, _, err := SelectKeysQuery(MySQL, "", []string{"id"}, data, []string{"id"}, false)
+		assert.NotNil(t, err)
+
+		_, _, err = SelectKeysQuery(MySQL, "user", []string{}, data, []string{"id"}, false)
+		assert.NotNil(t, err)
+
+		_, _, err = SelectKeysQuery(MySQL, "user", []string{"id"}, []map[string]any{}, []string{"id"}, false)
+		assert.NotNil(t, err)
+
+		_, _, err = SelectKeysQuery(MySQL, "user", []string{"id"}, data, []string{}, false)
+		assert.NotNil(t, err)
+
+		_, _, err = SelectKeysQuery(MySQL, "user", []string{"id"}, data, []string{"non_exists"}, false)
+		assert.NotNil(t, err)
+
+		_, _, err = SelectKeysQuery(MySQL, "user", []string{"COUNT(*)"}, data, []string{"id"}, false)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCountKeysQuery(t *testing.T) {
+	data := []map[string]any{{"id": 1, "name": "Name0"}, {"id": 2, "name": "Name1"}}
+	query, binds, err := CountKeysQuery(MySQL, "user", data, []string{"id"}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM `user` WHERE `id` IN (:id_0, :id_1) FOR UPDATE", query)
+	assert.Equal(t, map[string]any{"id_0": 1, "id_1": 2}, binds)
+
+	_, _, err = CountKeysQuery(MySQL, "user", data, []string{"id`; DROP TABLE user; --"}, true)
+	assert.NotNil(t, err)
+}
+
+func TestDeleteKeysQuery(t *testing.T) {
+	data := []map[string]any{{"id": 1, "code": "A"}, {"id": 2, "code": "B"}}
+	query, binds, err := DeleteKeysQuery(MySQL, "user", data, []string{"id"})
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM `user` WHERE `id` IN (:id_0, :id_1)", query)
+	assert.Equal(t, map[string]any{"id_0": 1, "id_1": 2}, binds)
+
+	query, binds, err = SoftDeleteKeysQuery(Postgres, "user", "deleted_at", data, []string{"id", "code"})
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" SET "deleted_at" = CURRENT_TIMESTAMP WHERE ("code", "id") IN ((:code_0, :id_0), (:code_1, :id_1)) AND "deleted_at" IS NULL`, query)
+	assert.Equal(t, map[string]any{"id_0": 1, "id_1": 2, "code_0": "A", "code_1": "B"}, binds)
+
+	_, _, err = DeleteKeysQuery(MySQL, "", data, []string{"id"})
+	assert.NotNil(t, err)
+	_, _, err = DeleteKeysQuery(MySQL, "user", data, []string{"name"})
+	assert.NotNil(t, err)
+	_, _, err = SoftDeleteKeysQuery(MySQL, "user", "deleted_at`; --", data, []string{"id"})
+	assert.NotNil(t, err)
+}
+
+func TestExpr(t *testing.T) {
+	t.Run("type cast", func(t *testing.T) {
+		testCases := []struct {
+			expr   Expr
+			query  string
+			result string
+			args   []any
+		}{
+			{
+				expr:   NewExpr(":v::int + 1", map[string]any{"v": "5"}),
+				query:  `UPDATE "product" SET "stock" = (:val_stock_v)::::int + 1 WHERE "id" = :cond_id`,
+				result: `UPDATE "product" SET "stock" = ($1)::int + 1 WHERE "id" = $2`,
+				args:   []any{"5", 1},
+			},
+			{
+				expr:   NewExpr("stock::numeric * :rate", map[string]any{"rate": 2}),
+				query:  `UPDATE "product" SET "stock" = stock::::numeric * :val_stock_rate WHERE "id" = :cond_id`,
+				result: `UPDATE "product" SET "stock" = stock::numeric * $1 WHERE "id" = $2`,
+				args:   []any{2, 1},
+			},
+		}
+		for index, testCase := range testCases {
+			t.Run(fmt.Sprintf("TestCase %d", index+1), func(t *testing.T) {
+				query, binds, err := UpdateQuery(Postgres, "product", map[string]any{"stock": testCase.expr}, map[string]any{"id": 1})
+				require.Nil(t, err)
+				assert.Equal(t, testCase.query, query)
+
+				named, args, err := sqlx.Named(query, binds)
+				require.Nil(t, err)
+				assert.Equal(t, testCase.result, sqlx.Rebind(sqlx.DOLLAR, named))
+				assert.Equal(t, testCase.args, args)
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		condition := map[string]any{"id": 1}
+
+		_, _, err := UpdateQuery(MySQL, "product", map[string]any{"stock": NewExpr("", nil)}, condition)
+		assert.NotNil(t, err)
+
+		_, _, err = UpdateQuery(MySQL, "product", map[string]any{"stock": NewExpr("stock - :n", nil)}, condition)
+		assert.NotNil(t, err)
+
+		_, _, err = UpdateQuery(MySQL, "product", map[string]any{"stock": NewExpr("stock - 1", map[string]any{"n": 1})}, condition)
+		assert.NotNil(t, err)
+
+		data := []map[string]any{{"id": 1, "stock": NewExpr("stock - 1", nil)}}
+		_, _, err = BulkUpdateValuesQuery(MySQL, "product", data, []string{"id"})
 		assert.NotNil(t, err)
 
-		_, _, err = ConditionQuery(map[string]any{"ids": []int{}})
+		_, _, err = BulkUpsertQuery(MySQL, "product", data, []string{"id"})
 		assert.NotNil(t, err)
 	})
 }
+
+func TestBulkUpdateQueryKeepsData(t *testing.T) {
+	testCases := []struct {
+		data     []map[string]any
+		keyEdits []string
+	}{
+		{
+			data:     []map[string]any{{"id": 1, "name": "Name1"}},
+			keyEdits: []string{"id"},
+		},
+		{
+			data:     []map[string]any{{"id": 1, "code": "A", "name": "Name1"}, {"id": 2, "code": "B", "name": "Name2"}},
+			keyEdits: []string{"id", "code"},
+		},
+		{
+			data:     []map[string]any{{"id": 1, "name": Optional[string]{}, "age": 20}},
+			keyEdits: []string{"id"},
+		},
+	}
+
+	for index, testCase := range testCases {
+		t.Run(fmt.Sprintf("TestCase %d", index), func(t *testing.T) {
+			expected := make([]map[string]any, len(testCase.data))
+			for index, item := range testCase.data {
+				expected[index] = map[string]any{}
+				for field, value := range item {
+					expected[index][field] = value
+				}
+			}
+
+			_, _, err := BulkUpdateQuery(MySQL, "user", testCase.data, testCase.keyEdits)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, testCase.data)
+		})
+	}
+}
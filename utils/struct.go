@@ -6,6 +6,9 @@ import (
 	"reflect"
 )
 
+// StructToMap is used to convert struct into map using tag as the key
+//
+// nil pointer is removed when removeNil is true, Optional field is removed when it is unset
 func StructToMap(payload any, tag string, removeNil bool) (map[string]any, error) {
 	result := map[string]any{}
 	v := reflect.ValueOf(payload)
@@ -21,6 +24,14 @@ func StructToMap(payload any, tag string, removeNil bool) (map[string]any, error
 			fieldName = typeField.Name
 		}
 
+		// Unset optional is never included, even removeNil is false
+		if o, ok := valueField.Interface().(optional); ok {
+			if !o.IsUnset() {
+				result[fieldName] = o.get()
+			}
+			continue
+		}
+
 		// Get pointer value
 		// Or get rid of fit
 		if valueField.Kind() == reflect.Ptr {
@@ -60,6 +60,21 @@ func TestStructToMap(t *testing.T) {
 		}
 	})
 
+	t.Run("optional", func(t *testing.T) {
+		type optionalType struct {
+			ID      int              `db:"id"`
+			Name    Optional[string] `db:"name"`
+			Age     Optional[int]    `db:"age"`
+			Address Optional[string] `db:"address"`
+		}
+		input := optionalType{ID: 1, Name: Null[string](), Age: Set(20)}
+		for _, removeNil := range []bool{true, false} {
+			actual, err := StructToMap(input, "db", removeNil)
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]any{"id": 1, "name": nil, "age": 20}, actual)
+		}
+	})
+
 	t.Run("failed", func(t *testing.T) {
 		testCases := []testCase{
 			{input: nil, expected: map[string]any{}},